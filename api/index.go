@@ -1,6 +1,11 @@
 package handler
 
 import (
+	"context"
+	"log"
+	"my-realm/internal/cache"
+	"my-realm/internal/config"
+	"my-realm/internal/utils"
 	"my-realm/src"
 	"my-realm/src/constants"
 	"net/http"
@@ -11,18 +16,51 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
+// init loads the configuration and picks the cache backend once per cold
+// start. Invalid settings are logged and left at their defaults, and a
+// backend that cannot be reached falls back to memory, rather than failing
+// every request.
+func init() {
+	env, err := config.Load()
+	if err != nil {
+		log.Printf("error loading configuration, using defaults: %v", err)
+		env = &config.Env{}
+	}
+	if err := env.Validate(); err != nil {
+		log.Printf("ignoring invalid settings: %v", err)
+	}
+	if env.GithubToken == "" {
+		log.Print("GITHUB_TOKEN is not set, GitHub stats will answer 503")
+	}
+	config.Set(env)
+	utils.Configure(env)
+
+	store, err := cache.New(env.CacheOptions())
+	if err != nil {
+		log.Printf("error setting up cache, using memory: %v", err)
+		return
+	}
+	utils.SetCache(store)
+}
+
 func Handler(w http.ResponseWriter, r *http.Request) {
 	r.RequestURI = r.URL.String()
 
-	handler().ServeHTTP(w, r)
+	handler(r.Context()).ServeHTTP(w, r)
 }
 
-func handler() http.HandlerFunc {
+// handler serves a request whose context is ctx. Upstream calls are made
+// with ctx, so they are abandoned when the client goes away.
+func handler(ctx context.Context) http.HandlerFunc {
 	app := fiber.New(fiber.Config{
 		ProxyHeader: "X-Forwarded-For",
 	})
 
 	app.Use(cors.New())
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(ctx)
+		return c.Next()
+	})
 
 	app.Get("/api/health", func(c *fiber.Ctx) error {
 		return c.JSON(constants.Response{
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"context"
+	"log"
+	"my-realm/internal/cache"
+	"my-realm/internal/config"
+	"my-realm/internal/history"
+	"my-realm/internal/utils"
 	"my-realm/src"
 	"my-realm/src/constants"
 	"time"
@@ -9,6 +15,38 @@ import (
 )
 
 func main() {
+	env, err := config.Load()
+	if err != nil {
+		panic(err)
+	}
+	if err := env.Validate(); err != nil {
+		log.Printf("ignoring invalid settings: %v", err)
+	}
+	if env.GithubToken == "" {
+		log.Print("GITHUB_TOKEN is not set, GitHub stats will answer 503")
+	}
+	config.Set(env)
+	utils.Configure(env)
+
+	if store, err := cache.New(env.CacheOptions()); err != nil {
+		log.Printf("error setting up cache, using memory: %v", err)
+	} else {
+		utils.SetCache(store)
+	}
+
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go utils.RunRefresher(ctx, env.RefreshOptions())
+
+	if env.HistoryDB != "" {
+		if historyStore, err := history.NewSQLite(env.HistoryDB); err != nil {
+			log.Printf("error opening history database, history is disabled: %v", err)
+		} else {
+			utils.SetHistory(historyStore)
+			go utils.RunHistoryRecorder(ctx, env.TrackedUsers(), env.HistoryRetentionPeriod(), time.Hour)
+		}
+	}
+
 	app := fiber.New()
 
 	app.Get("/api/health", func(c *fiber.Ctx) error {
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultMaxEntries caps the in-memory cache when no size is configured.
+const DefaultMaxEntries = 1000
+
+// Cache stores encoded values with a per-entry TTL. A TTL of zero or less
+// keeps the entry until it is evicted or deleted.
+//
+// Get reports a miss for anything it cannot read, including backend errors,
+// since callers can always fall back to fetching the value again.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+type Options struct {
+	// Backend is one of "memory", "file" or "redis". Empty means "memory".
+	Backend    string
+	MaxEntries int
+	Dir        string
+	RedisURL   string
+}
+
+// New builds the cache described by opts.
+func New(opts Options) (Cache, error) {
+	switch opts.Backend {
+	case "", "memory":
+		return NewMemory(opts.MaxEntries), nil
+	case "file":
+		dir := opts.Dir
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "my-realm-cache")
+		}
+		return NewFile(dir)
+	case "redis":
+		if opts.RedisURL == "" {
+			return nil, fmt.Errorf("redis cache needs REDIS_URL")
+		}
+		return NewRedis(opts.RedisURL)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", opts.Backend)
+	}
+}
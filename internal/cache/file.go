@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File keeps one file per entry in dir, so entries survive restarts of the
+// process. Each file starts with the entry's expiry as 8 bytes of Unix
+// nanoseconds, zero meaning no expiry, followed by the value.
+type File struct {
+	dir string
+}
+
+func NewFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+	return &File{dir: dir}, nil
+}
+
+func (f *File) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(f.path(key))
+	if err != nil || len(data) < 8 {
+		return nil, false
+	}
+
+	if expiresAt := int64(binary.BigEndian.Uint64(data)); expiresAt != 0 && time.Now().UnixNano() > expiresAt {
+		_ = f.Delete(key)
+		return nil, false
+	}
+	return data[8:], true
+}
+
+func (f *File) Set(key string, value []byte, ttl time.Duration) error {
+	data := make([]byte, 8, 8+len(value))
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(data, uint64(time.Now().Add(ttl).UnixNano()))
+	}
+	data = append(data, value...)
+
+	// Write to a temporary file first so readers never see a partial entry.
+	tmp, err := os.CreateTemp(f.dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(key)); err != nil {
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	return nil
+}
+
+func (f *File) Delete(key string) error {
+	if err := os.Remove(f.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error deleting cache file: %w", err)
+	}
+	return nil
+}
+
+func (f *File) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Set("github:alice", []byte(`{"stars":42}`), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := f.Set("brief", []byte("soon gone"), time.Nanosecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// A second cache on the same directory stands in for a restart.
+	reopened, err := NewFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := reopened.Get("github:alice"); !ok || string(value) != `{"stars":42}` {
+		t.Errorf("Get after reopening = %q, %v, want the stored value", value, ok)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok := reopened.Get("brief"); ok {
+		t.Error("Get(brief) reported a hit after its TTL")
+	}
+
+	if err := reopened.Delete("github:alice"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := f.Get("github:alice"); ok {
+		t.Error("Get reported a hit after Delete")
+	}
+	if err := f.Delete("github:alice"); err != nil {
+		t.Errorf("Delete of a missing entry: %v", err)
+	}
+}
+
+func TestFileTreatsCorruptEntriesAsMisses(t *testing.T) {
+	f, err := NewFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range []string{"", "short"} {
+		if err := os.WriteFile(f.path("corrupt"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if value, ok := f.Get("corrupt"); ok {
+			t.Errorf("Get on a %d byte file = %q, want a miss", len(data), value)
+		}
+	}
+
+	if err := f.Set("corrupt", []byte("fixed"), 0); err != nil {
+		t.Fatalf("Set over a corrupt entry: %v", err)
+	}
+	if value, ok := f.Get("corrupt"); !ok || string(value) != "fixed" {
+		t.Errorf("Get after Set = %q, %v, want the new value", value, ok)
+	}
+}
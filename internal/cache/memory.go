@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Memory is a process-local LRU cache holding at most maxEntries entries.
+type Memory struct {
+	mu         sync.Mutex
+	maxEntries int
+	items      map[string]*list.Element
+	order      *list.List
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Memory{
+		maxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, exists := m.items[key]
+	if !exists {
+		return nil, false
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		m.remove(element)
+		return nil, false
+	}
+
+	m.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if element, exists := m.items[key]; exists {
+		entry := element.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.items[key] = m.order.PushFront(&memoryEntry{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+	for m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, exists := m.items[key]; exists {
+		m.remove(element)
+	}
+	return nil
+}
+
+func (m *Memory) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.items, element.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryGetSetAndExpiry(t *testing.T) {
+	m := NewMemory(10)
+
+	if _, ok := m.Get("missing"); ok {
+		t.Error("Get on an empty cache reported a hit")
+	}
+
+	m.Set("kept", []byte("forever"), 0)
+	m.Set("brief", []byte("soon gone"), 10*time.Millisecond)
+	if value, ok := m.Get("brief"); !ok || string(value) != "soon gone" {
+		t.Errorf("Get(brief) = %q, %v, want the value before it expires", value, ok)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := m.Get("brief"); ok {
+		t.Error("Get(brief) reported a hit after its TTL")
+	}
+	if value, ok := m.Get("kept"); !ok || string(value) != "forever" {
+		t.Errorf("Get(kept) = %q, %v, want an entry without a TTL kept", value, ok)
+	}
+
+	m.Set("kept", []byte("replaced"), 0)
+	if value, _ := m.Get("kept"); string(value) != "replaced" {
+		t.Errorf("Get(kept) after Set = %q, want replaced", value)
+	}
+	m.Delete("kept")
+	if _, ok := m.Get("kept"); ok {
+		t.Error("Get(kept) reported a hit after Delete")
+	}
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	m := NewMemory(2)
+
+	m.Set("a", []byte("1"), 0)
+	m.Set("b", []byte("2"), 0)
+	m.Get("a")
+	m.Set("c", []byte("3"), 0)
+
+	if _, ok := m.Get("b"); ok {
+		t.Error("least recently used entry was kept past MaxEntries")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := m.Get(key); !ok {
+			t.Errorf("Get(%s) missed, want it kept", key)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisKeyPrefix = "my-realm:"
+	redisTimeout   = 2 * time.Second
+)
+
+var errRedisNil = errors.New("redis: nil reply")
+
+// Redis is a cache shared by every instance of the server, talking RESP over
+// a single connection that is redialed after any error.
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	useTLS   bool
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis connects to rawURL, in the form redis://[user:password@]host:port[/db]
+// or rediss:// for TLS.
+func NewRedis(rawURL string) (*Redis, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing REDIS_URL: %w", err)
+	}
+	if parsed.Scheme != "redis" && parsed.Scheme != "rediss" {
+		return nil, fmt.Errorf("unsupported REDIS_URL scheme %q", parsed.Scheme)
+	}
+
+	r := &Redis{
+		addr:   parsed.Host,
+		useTLS: parsed.Scheme == "rediss",
+	}
+	if parsed.Port() == "" {
+		r.addr = net.JoinHostPort(parsed.Hostname(), "6379")
+	}
+	if parsed.User != nil {
+		r.username = parsed.User.Username()
+		r.password, _ = parsed.User.Password()
+	}
+	if db := strings.TrimPrefix(parsed.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Redis) Get(key string) ([]byte, bool) {
+	reply, err := r.do("GET", redisKeyPrefix+key)
+	if err != nil {
+		return nil, false
+	}
+	value, ok := reply.([]byte)
+	return value, ok
+}
+
+func (r *Redis) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", redisKeyPrefix + key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := r.do(args...)
+	return err
+}
+
+func (r *Redis) Delete(key string) error {
+	_, err := r.do("DEL", redisKeyPrefix+key)
+	return err
+}
+
+func (r *Redis) do(args ...string) (any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		if err := r.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := r.roundTrip(args...)
+	if errors.Is(err, errRedisNil) {
+		return nil, nil
+	}
+	if err != nil {
+		var replyErr redisError
+		if !errors.As(err, &replyErr) {
+			// The connection is in an unknown state; start over next time.
+			r.conn.Close()
+			r.conn = nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// connect dials the server and authenticates. The caller must hold r.mu.
+func (r *Redis) connect() error {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+
+	var conn net.Conn
+	var err error
+	if r.useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", r.addr, &tls.Config{MinVersion: tls.VersionTLS12})
+	} else {
+		conn, err = dialer.Dial("tcp", r.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("error connecting to redis: %w", err)
+	}
+
+	r.conn = conn
+	r.reader = bufio.NewReader(conn)
+
+	var setup [][]string
+	if r.password != "" {
+		if r.username != "" {
+			setup = append(setup, []string{"AUTH", r.username, r.password})
+		} else {
+			setup = append(setup, []string{"AUTH", r.password})
+		}
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	for _, args := range setup {
+		if _, err := r.roundTrip(args...); err != nil {
+			conn.Close()
+			r.conn = nil
+			return fmt.Errorf("error setting up redis connection: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *Redis) roundTrip(args ...string) (any, error) {
+	if err := r.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(r.conn, command.String()); err != nil {
+		return nil, fmt.Errorf("error writing redis command: %w", err)
+	}
+
+	return r.readReply()
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (r *Redis) readReply() (any, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("error reading redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis bulk length: %w", err)
+		}
+		if size < 0 {
+			return nil, errRedisNil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r.reader, data); err != nil {
+			return nil, fmt.Errorf("error reading redis reply: %w", err)
+		}
+		return data[:size], nil
+	default:
+		return nil, fmt.Errorf("unsupported redis reply %q", line)
+	}
+}
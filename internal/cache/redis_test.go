@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisGetSetAndExpiry(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+
+	r, err := NewRedis("redis://:secret@" + server.Addr() + "/2")
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+
+	if _, ok := r.Get("missing"); ok {
+		t.Error("Get of a missing key reported a hit")
+	}
+	if err := r.Set("github:alice", []byte("stats\r\nwith a line break"), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if value, ok := r.Get("github:alice"); !ok || string(value) != "stats\r\nwith a line break" {
+		t.Errorf("Get = %q, %v, want the stored value", value, ok)
+	}
+
+	server.Select(2)
+	if !server.Exists(redisKeyPrefix + "github:alice") {
+		t.Errorf("keys in database 2 = %v, want the entry under the %q prefix", server.Keys(), redisKeyPrefix)
+	}
+	server.FastForward(2 * time.Minute)
+	if _, ok := r.Get("github:alice"); ok {
+		t.Error("Get reported a hit after the TTL")
+	}
+
+	r.Set("kept", []byte("value"), 0)
+	if err := r.Delete("kept"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := r.Get("kept"); ok {
+		t.Error("Get reported a hit after Delete")
+	}
+}
+
+func TestRedisReconnectsAfterConnectionLoss(t *testing.T) {
+	server := miniredis.RunT(t)
+	r, err := NewRedis("redis://" + server.Addr())
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+
+	r.Set("key", []byte("value"), 0)
+	addr := server.Addr()
+	server.Close()
+	if _, ok := r.Get("key"); ok {
+		t.Error("Get reported a hit with the server down")
+	}
+
+	if err := server.StartAddr(addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Set("key", []byte("again"), 0); err != nil {
+		t.Fatalf("Set after the server came back: %v", err)
+	}
+	if value, ok := r.Get("key"); !ok || string(value) != "again" {
+		t.Errorf("Get after reconnecting = %q, %v, want again", value, ok)
+	}
+}
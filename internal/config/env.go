@@ -1,22 +1,568 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"my-realm/internal/cache"
+	"my-realm/internal/models"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
 type Env struct {
-	GithubToken string `mapstructure:"GITHUB_TOKEN"`
+	GithubToken      string `mapstructure:"GITHUB_TOKEN"`
+	GithubAPIURL     string `mapstructure:"GITHUB_API_URL"`
+	GithubGraphQLURL string `mapstructure:"GITHUB_GRAPHQL_URL"`
+	GithubInstances  string `mapstructure:"GITHUB_INSTANCES"`
+	StackExchangeKey string `mapstructure:"STACKEXCHANGE_KEY"`
+	WakaTimeAPIKey   string `mapstructure:"WAKATIME_API_KEY"`
+	FeedAllowedHosts string `mapstructure:"FEED_ALLOWED_HOSTS"`
+	CacheBackend     string `mapstructure:"CACHE_BACKEND"`
+	CacheDir         string `mapstructure:"CACHE_DIR"`
+	RedisURL         string `mapstructure:"REDIS_URL"`
+	HistoryDB        string `mapstructure:"HISTORY_DB"`
+	HistoryUsers     string `mapstructure:"HISTORY_USERS"`
+
+	// values holds every setting by its upper case key, for the ones named
+	// after a provider or GitHub host.
+	values map[string]string
+
+	// The settings below are parsed once by Load. Those left unset or with
+	// an invalid value are nil, or zero where zero means unset, and the
+	// invalid ones are kept in errs for Validate.
+	cacheMaxEntries    int
+	maxStaleness       *time.Duration
+	cardTTL            *time.Duration
+	cardCacheMin       *int
+	cardCacheMax       *int
+	refreshHottest     *int
+	refreshInterval    *time.Duration
+	refreshConcurrency *int
+	refreshReserve     *int
+	historyRetention   *int
+	upstreamTimeout    time.Duration
+	upstreamRetries    *int
+	breakerThreshold   *int
+	breakerCooldown    time.Duration
+	upstreamTimeouts   map[string]time.Duration
+	cacheTTLs          map[string]time.Duration
+	upstreamURLs       map[string]string
+	githubURLs         map[string]string
+	cardPolicies       map[string]map[string]int
+	errs               []error
+}
+
+var current atomic.Pointer[Env]
+
+func init() {
+	current.Store(&Env{})
+}
+
+// Current is the configuration the server was started with.
+func Current() *Env {
+	return current.Load()
+}
+
+// Set replaces the configuration returned by Current. It is meant to be
+// called once at startup, with the result of Load.
+func Set(env *Env) {
+	current.Store(env)
+}
+
+// Load reads the configuration from a .env file in the working directory, the
+// YAML or TOML file named by CONFIG_FILE in either, and the environment, each
+// overriding the ones before it. Keys are the environment variable names,
+// case insensitive, at the top level of the file. A variable that is set
+// overrides the files even when it is empty. A missing .env is skipped;
+// settings with invalid values are reported by Validate.
+func Load() (*Env, error) {
+	v := viper.New()
+
+	v.SetConfigFile(".env")
+	v.SetConfigType("env")
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error reading .env: %w", err)
+	}
+
+	file, set := os.LookupEnv("CONFIG_FILE")
+	if !set {
+		file = v.GetString("CONFIG_FILE")
+	}
+	if file != "" {
+		v.SetConfigFile(file)
+		v.SetConfigType(strings.TrimPrefix(filepath.Ext(file), "."))
+		if err := v.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", file, err)
+		}
+	}
+
+	for _, variable := range os.Environ() {
+		key, value, _ := strings.Cut(variable, "=")
+		if key != "" && !strings.Contains(key, ".") {
+			v.Set(key, value)
+		}
+	}
+
+	env := Env{values: make(map[string]string)}
+	for _, key := range v.AllKeys() {
+		env.values[strings.ToUpper(key)] = v.GetString(key)
+	}
+	if err := v.Unmarshal(&env); err != nil {
+		return nil, fmt.Errorf("error decoding configuration: %w", err)
+	}
+	env.parse()
+	return &env, nil
+}
+
+// lookup returns the setting called key, e.g. "GITHUB_WORK_TOKEN".
+func (env *Env) lookup(key string) string {
+	return env.values[key]
+}
+
+// withPrefix returns the settings whose key starts with prefix, by the rest
+// of their key in lower case.
+func (env *Env) withPrefix(prefix string) map[string]string {
+	settings := make(map[string]string)
+	for key, value := range env.values {
+		if name, found := strings.CutPrefix(key, prefix); found && name != "" {
+			settings[strings.ToLower(name)] = value
+		}
+	}
+	return settings
+}
+
+// parse reads the numeric, duration and URL settings into their fields.
+func (env *Env) parse() {
+	env.cacheMaxEntries, _ = env.count("CACHE_MAX_ENTRIES")
+	env.maxStaleness = optional(env.duration("CACHE_MAX_STALENESS"))
+	env.cardTTL = optional(env.duration("CARD_TTL"))
+	env.cardCacheMin = optional(env.count("CARD_CACHE_MIN_SECONDS"))
+	env.cardCacheMax = optional(env.count("CARD_CACHE_MAX_SECONDS"))
+	env.refreshHottest = optional(env.count("REFRESH_HOTTEST"))
+	env.refreshInterval = optional(env.duration("REFRESH_INTERVAL"))
+	env.refreshConcurrency = optional(env.count("REFRESH_CONCURRENCY"))
+	env.refreshReserve = optional(env.count("REFRESH_GITHUB_RESERVE"))
+	env.historyRetention = optional(env.count("HISTORY_RETENTION_DAYS"))
+	env.upstreamTimeout, _ = env.duration("UPSTREAM_TIMEOUT")
+	env.upstreamRetries = optional(env.count("UPSTREAM_RETRIES"))
+	env.breakerThreshold = optional(env.count("UPSTREAM_BREAKER_THRESHOLD"))
+	env.breakerCooldown, _ = env.duration("UPSTREAM_BREAKER_COOLDOWN")
+	env.upstreamTimeouts = env.durations("UPSTREAM_TIMEOUT_")
+	env.cacheTTLs = env.durations("CACHE_TTL_")
+
+	env.githubURLs = make(map[string]string)
+	apiURL, _ := env.url("GITHUB_API_URL")
+	graphQLURL, _ := env.url("GITHUB_GRAPHQL_URL")
+	env.githubURLs["GITHUB_API_URL"] = apiURL
+	env.githubURLs["GITHUB_GRAPHQL_URL"] = graphQLURLFor(apiURL, graphQLURL)
+	if apiURL != "" && env.githubURLs["GITHUB_GRAPHQL_URL"] == "" {
+		env.errs = append(env.errs, errors.New("GITHUB_GRAPHQL_URL: required with GITHUB_API_URL, as it can't be derived from it"))
+	}
+	for _, name := range splitList(env.GithubInstances) {
+		prefix := "GITHUB_" + EnvName(name) + "_"
+		apiURL, _ := env.url(prefix + "API_URL")
+		graphQLURL, _ := env.url(prefix + "GRAPHQL_URL")
+		env.githubURLs[prefix+"API_URL"] = apiURL
+		env.githubURLs[prefix+"GRAPHQL_URL"] = graphQLURLFor(apiURL, graphQLURL)
+		switch {
+		case env.lookup(prefix+"API_URL") == "":
+			env.errs = append(env.errs, fmt.Errorf("%sAPI_URL: required for the GitHub instance %q", prefix, name))
+		case apiURL != "" && env.githubURLs[prefix+"GRAPHQL_URL"] == "":
+			env.errs = append(env.errs, fmt.Errorf("%sGRAPHQL_URL: required for the GitHub instance %q, as it can't be derived from %sAPI_URL", prefix, name, prefix))
+		}
+	}
+	env.cardPolicies = make(map[string]map[string]int)
+	for name, value := range env.withPrefix("CARD_CACHE_") {
+		// The cache_seconds bounds share the prefix but aren't cards.
+		if name == "min_seconds" || name == "max_seconds" {
+			continue
+		}
+		env.cardPolicies[EnvName(name)] = cacheDirectives(value)
+	}
+
+	env.upstreamURLs = make(map[string]string)
+	for _, key := range slices.Sorted(maps.Keys(upstreamURLs(&models.Upstreams{}))) {
+		if value, ok := env.url(key); ok {
+			env.upstreamURLs[key] = value
+		}
+	}
+}
+
+// count parses the setting called key as a count. ok is false when it is
+// unset or invalid.
+func (env *Env) count(key string) (count int, ok bool) {
+	value := env.lookup(key)
+	if value == "" {
+		return 0, false
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		env.invalid(key, value)
+		return 0, false
+	}
+	return count, true
+}
+
+// duration parses the setting called key as a duration, e.g. "90s". ok is
+// false when it is unset or invalid.
+func (env *Env) duration(key string) (duration time.Duration, ok bool) {
+	value := env.lookup(key)
+	if value == "" {
+		return 0, false
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		env.invalid(key, value)
+		return 0, false
+	}
+	return duration, true
+}
+
+// durations parses the durations set per provider as prefix<NAME>, by the
+// name in lower case.
+func (env *Env) durations(prefix string) map[string]time.Duration {
+	settings := env.withPrefix(prefix)
+	durations := make(map[string]time.Duration, len(settings))
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		if duration, ok := env.duration(prefix + EnvName(name)); ok {
+			durations[name] = duration
+		}
+	}
+	return durations
+}
+
+// url checks the setting called key is an absolute http or https URL and
+// returns it without a trailing slash. ok is false when it is unset or
+// invalid.
+func (env *Env) url(key string) (string, bool) {
+	value := env.lookup(key)
+	if value == "" {
+		return "", false
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		env.invalid(key, value)
+		return "", false
+	}
+	return strings.TrimSuffix(value, "/"), true
+}
+
+func (env *Env) invalid(key, value string) {
+	env.errs = append(env.errs, fmt.Errorf("%s: invalid value %q", key, value))
+}
+
+// optional returns value when ok, for the settings whose zero value means
+// something.
+func optional[T any](value T, ok bool) *T {
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+// Validate reports every setting Load found unusable. Such settings are
+// ignored in favor of their default.
+func (env *Env) Validate() error {
+	return errors.Join(env.errs...)
+}
+
+// CacheOptions describes the cache backend selected by CACHE_BACKEND.
+func (env *Env) CacheOptions() cache.Options {
+	return cache.Options{
+		Backend:    env.CacheBackend,
+		MaxEntries: env.cacheMaxEntries,
+		Dir:        env.CacheDir,
+		RedisURL:   env.RedisURL,
+	}
+}
+
+// MaxStaleness is CACHE_MAX_STALENESS, e.g. "6h". ok is false when it is
+// unset or invalid.
+func (env *Env) MaxStaleness() (maxStaleness time.Duration, ok bool) {
+	if env.maxStaleness == nil {
+		return 0, false
+	}
+	return *env.maxStaleness, true
+}
+
+// UpstreamTimeouts applies UPSTREAM_TIMEOUT, e.g. "8s", to fallback and the
+// per-upstream UPSTREAM_TIMEOUT_<NAME> overrides, e.g.
+// UPSTREAM_TIMEOUT_WAKATIME="30s", to timeouts. Invalid values are ignored.
+func (env *Env) UpstreamTimeouts(fallback time.Duration, timeouts map[string]time.Duration) (time.Duration, map[string]time.Duration) {
+	if env.upstreamTimeout > 0 {
+		fallback = env.upstreamTimeout
+	}
+	return fallback, mergePositive(timeouts, env.upstreamTimeouts)
+}
+
+// TransportOptions applies UPSTREAM_RETRIES, UPSTREAM_BREAKER_THRESHOLD (0
+// turns the circuit breaker off) and UPSTREAM_BREAKER_COOLDOWN, e.g. "1m", to
+// defaults. Invalid values are ignored.
+func (env *Env) TransportOptions(defaults models.TransportOptions) models.TransportOptions {
+	opts := defaults
+	if env.upstreamRetries != nil {
+		opts.MaxRetries = *env.upstreamRetries
+	}
+	if env.breakerThreshold != nil {
+		opts.BreakerThreshold = *env.breakerThreshold
+	}
+	if env.breakerCooldown > 0 {
+		opts.BreakerCooldown = env.breakerCooldown
+	}
+	return opts
+}
+
+// CacheTTLs applies the CACHE_TTL_<PROVIDER> overrides, e.g.
+// CACHE_TTL_GITHUB="30m", to ttls. Invalid values are ignored.
+func (env *Env) CacheTTLs(ttls map[string]time.Duration) map[string]time.Duration {
+	return mergePositive(ttls, env.cacheTTLs)
+}
+
+// mergePositive copies defaults with the positive overrides applied.
+func mergePositive(defaults, overrides map[string]time.Duration) map[string]time.Duration {
+	merged := maps.Clone(defaults)
+	if merged == nil {
+		merged = make(map[string]time.Duration, len(overrides))
+	}
+	for name, duration := range overrides {
+		if duration > 0 {
+			merged[name] = duration
+		}
+	}
+	return merged
+}
+
+// RenderedCardTTL is CARD_TTL, how long a rendered card is reused. ok is
+// false when it is unset or invalid.
+func (env *Env) RenderedCardTTL() (ttl time.Duration, ok bool) {
+	if env.cardTTL == nil {
+		return 0, false
+	}
+	return *env.cardTTL, true
+}
+
+// Upstreams applies the base URL overrides, e.g. LEETCODE_GRAPHQL_URL, to
+// defaults. GitHub is configured per host instead, see GitHubInstance.
+func (env *Env) Upstreams(defaults models.Upstreams) models.Upstreams {
+	upstreams := defaults
+	for key, target := range upstreamURLs(&upstreams) {
+		if value, exists := env.upstreamURLs[key]; exists {
+			*target = value
+		}
+	}
+	return upstreams
+}
+
+// upstreamURLs maps the setting of each upstream base URL onto its field.
+func upstreamURLs(u *models.Upstreams) map[string]*string {
+	return map[string]*string{
+		"LEETCODE_GRAPHQL_URL": &u.LeetCodeGraphQL,
+		"ATCODER_URL":          &u.AtCoder,
+		"ATCODER_PROBLEMS_URL": &u.AtCoderProblems,
+		"CODECHEF_URL":         &u.CodeChef,
+		"STACKEXCHANGE_URL":    &u.StackExchange,
+		"WAKATIME_URL":         &u.WakaTime,
+		"EXERCISM_URL":         &u.Exercism,
+		"CODEWARS_URL":         &u.Codewars,
+		"DEVTO_URL":            &u.DevTo,
+		"HASHNODE_URL":         &u.Hashnode,
+		"NPM_REGISTRY_URL":     &u.NpmRegistry,
+		"NPM_DOWNLOADS_URL":    &u.NpmDownloads,
+		"PYPI_URL":             &u.PyPI,
+		"PYPISTATS_URL":        &u.PyPIStats,
+		"CRATES_URL":           &u.CratesIO,
+		"GOPROXY_URL":          &u.GoProxy,
+	}
+}
+
+// CardCachePolicy applies the Cache-Control override for the card called name
+// to defaults. Overrides are set as CARD_CACHE_<NAME>, e.g.
+// CARD_CACHE_STATS="max-age=300, s-maxage=1800, stale-while-revalidate=86400";
+// directives left out keep their default.
+func (env *Env) CardCachePolicy(name string, defaults models.CachePolicy) models.CachePolicy {
+	policy := defaults
+	for directive, seconds := range env.cardPolicies[EnvName(name)] {
+		switch directive {
+		case "max-age":
+			policy.MaxAge = seconds
+		case "s-maxage":
+			policy.SMaxAge = seconds
+		case "stale-while-revalidate":
+			policy.StaleWhileRevalidate = seconds
+		}
+	}
+	return policy
+}
+
+// cacheDirectives reads the directives with a number of seconds from a
+// Cache-Control value, by their name in lower case.
+func cacheDirectives(value string) map[string]int {
+	directives := make(map[string]int)
+	for _, directive := range strings.Split(value, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(directive), "=")
+		seconds, err := strconv.Atoi(value)
+		if found && err == nil && seconds >= 0 {
+			directives[strings.ToLower(key)] = seconds
+		}
+	}
+	return directives
+}
+
+// CardCacheBounds returns the range the cache_seconds query parameter is
+// clamped to, from CARD_CACHE_MIN_SECONDS and CARD_CACHE_MAX_SECONDS.
+func (env *Env) CardCacheBounds() (minSeconds, maxSeconds int) {
+	minSeconds, maxSeconds = 60, 86400
+	if env.cardCacheMin != nil {
+		minSeconds = *env.cardCacheMin
+	}
+	if env.cardCacheMax != nil && *env.cardCacheMax >= minSeconds {
+		maxSeconds = *env.cardCacheMax
+	}
+	return minSeconds, maxSeconds
+}
+
+// RefreshOptions configures the background refresh of popular entries from
+// REFRESH_HOTTEST (50 unless set, 0 turns it off), REFRESH_INTERVAL (1m),
+// REFRESH_CONCURRENCY (4) and REFRESH_GITHUB_RESERVE (500).
+func (env *Env) RefreshOptions() models.RefreshOptions {
+	opts := models.RefreshOptions{
+		Hottest:       50,
+		Interval:      time.Minute,
+		Concurrency:   4,
+		GitHubReserve: 500,
+	}
+	if env.refreshHottest != nil {
+		opts.Hottest = *env.refreshHottest
+	}
+	if env.refreshInterval != nil && *env.refreshInterval >= time.Second {
+		opts.Interval = *env.refreshInterval
+	}
+	if env.refreshConcurrency != nil && *env.refreshConcurrency > 0 {
+		opts.Concurrency = *env.refreshConcurrency
+	}
+	if env.refreshReserve != nil {
+		opts.GitHubReserve = *env.refreshReserve
+	}
+	return opts
+}
+
+// TrackedUsers parses HISTORY_USERS, a comma separated list of
+// provider:username pairs, e.g. "github:octocat,leetcode:alice".
+func (env *Env) TrackedUsers() []models.TrackedUser {
+	var tracked []models.TrackedUser
+	for _, item := range strings.Split(env.HistoryUsers, ",") {
+		provider, username, found := strings.Cut(strings.TrimSpace(item), ":")
+		if found && provider != "" && username != "" {
+			tracked = append(tracked, models.TrackedUser{
+				Provider: strings.ToLower(provider),
+				Username: username,
+			})
+		}
+	}
+	return tracked
+}
+
+// HistoryRetentionPeriod is how long snapshots are kept, HISTORY_RETENTION_DAYS
+// (365 unless set, 0 keeps them forever).
+func (env *Env) HistoryRetentionPeriod() time.Duration {
+	days := 365
+	if env.historyRetention != nil {
+		days = *env.historyRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
 }
 
-func LoadEnv() *Env {
-	env := Env{
-		GithubToken: os.Getenv("GITHUB_TOKEN"),
+// GitHubInstance returns the GitHub host called name. The empty name is the
+// default host, configured by GITHUB_TOKEN, GITHUB_API_URL and
+// GITHUB_GRAPHQL_URL. Other names must be listed in GITHUB_INSTANCES and are
+// configured by GITHUB_<NAME>_TOKEN, GITHUB_<NAME>_API_URL and
+// GITHUB_<NAME>_GRAPHQL_URL. Either token may be a comma separated list.
+// ok is false for a named host without its own URLs, so its token is never
+// sent to github.com.
+func (env *Env) GitHubInstance(name string) (models.GitHubInstance, bool) {
+	if name == "" {
+		return models.GitHubInstance{
+			APIURL:     env.githubURLs["GITHUB_API_URL"],
+			GraphQLURL: env.githubURLs["GITHUB_GRAPHQL_URL"],
+			Tokens:     splitTokens(env.GithubToken),
+		}, true
 	}
 
-	if env.GithubToken == "" {
-		log.Fatal("GITHUB_TOKEN is not set")
+	name = strings.ToLower(name)
+	if !slices.Contains(splitList(env.GithubInstances), name) {
+		return models.GitHubInstance{}, false
 	}
 
-	return &env
+	prefix := "GITHUB_" + EnvName(name) + "_"
+	apiURL := env.githubURLs[prefix+"API_URL"]
+	graphQLURL := env.githubURLs[prefix+"GRAPHQL_URL"]
+	if apiURL == "" || graphQLURL == "" {
+		return models.GitHubInstance{}, false
+	}
+	return models.GitHubInstance{
+		Name:       name,
+		APIURL:     apiURL,
+		GraphQLURL: graphQLURL,
+		Tokens:     splitTokens(env.lookup(prefix + "TOKEN")),
+	}, true
+}
+
+// GitHubInstances returns the default GitHub host followed by every host
+// listed in GITHUB_INSTANCES.
+func (env *Env) GitHubInstances() []models.GitHubInstance {
+	instances := make([]models.GitHubInstance, 0, 1)
+	for _, name := range append([]string{""}, splitList(env.GithubInstances)...) {
+		if instance, ok := env.GitHubInstance(name); ok {
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
+
+// graphQLURLFor derives a GitHub Enterprise Server GraphQL endpoint from its
+// REST one, e.g. https://ghe.example.com/api/v3 -> https://ghe.example.com/api/graphql.
+// It is empty when graphQLURL is unset and apiURL has another path.
+func graphQLURLFor(apiURL, graphQLURL string) string {
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	if graphQLURL != "" || !strings.HasSuffix(apiURL, "/api/v3") {
+		return graphQLURL
+	}
+	return strings.TrimSuffix(apiURL, "/v3") + "/graphql"
+}
+
+// EnvName turns a name into the form used inside environment variable names,
+// e.g. "GITHUB_" + EnvName("my-work") + "_TOKEN".
+func EnvName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// splitTokens reads a comma separated list of tokens.
+func splitTokens(value string) []string {
+	var tokens []string
+	for _, token := range strings.Split(value, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
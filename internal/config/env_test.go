@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"my-realm/internal/models"
+)
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayersSources(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	writeFile(t, ".env", `GITHUB_TOKEN="from-dotenv"
+WAKATIME_API_KEY="waka-dotenv"
+STACKEXCHANGE_KEY="se-dotenv"
+CACHE_TTL_GITHUB="5m"
+`)
+	writeFile(t, "settings.yaml", `wakatime_api_key: waka-yaml
+refresh_hottest: 10
+leetcode_graphql_url: https://leetcode.example.com/graphql/
+`)
+	t.Setenv("CONFIG_FILE", filepath.Join(dir, "settings.yaml"))
+	t.Setenv("REFRESH_HOTTEST", "20")
+	t.Setenv("STACKEXCHANGE_KEY", "")
+	t.Setenv("GITHUB_WORK_TOKEN", "ghe-token")
+	t.Setenv("GITHUB_WORK_API_URL", "https://ghe.example.com/api/v3")
+	t.Setenv("GITHUB_INSTANCES", "work")
+	t.Setenv("GITHUB_API_URL", "https://ghe.example.com/api/v3/")
+
+	env, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := env.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	if env.GithubToken != "from-dotenv" || env.WakaTimeAPIKey != "waka-yaml" {
+		t.Errorf("tokens = %q, %q, want the .env one and the file one overriding .env", env.GithubToken, env.WakaTimeAPIKey)
+	}
+	if env.StackExchangeKey != "" {
+		t.Errorf("Stack Exchange key = %q, want the empty variable overriding .env", env.StackExchangeKey)
+	}
+	if opts := env.RefreshOptions(); opts.Hottest != 20 {
+		t.Errorf("hottest = %d, want the environment overriding the file", opts.Hottest)
+	}
+	if instance, _ := env.GitHubInstance(""); instance.GraphQLURL != "https://ghe.example.com/api/graphql" {
+		t.Errorf("default instance GraphQL URL = %q, want it derived from the API URL despite its trailing slash", instance.GraphQLURL)
+	}
+	if instance, ok := env.GitHubInstance("work"); !ok || len(instance.Tokens) != 1 || instance.Tokens[0] != "ghe-token" {
+		t.Errorf("work instance = %+v, want its token from the environment", instance)
+	}
+	if ttls := env.CacheTTLs(map[string]time.Duration{"github": 10 * time.Minute, "feed": time.Hour}); ttls["github"] != 5*time.Minute || ttls["feed"] != time.Hour {
+		t.Errorf("cache TTLs = %v, want github overridden and feed kept", ttls)
+	}
+	upstreams := env.Upstreams(models.Upstreams{LeetCodeGraphQL: "https://leetcode.com/graphql", AtCoder: "https://atcoder.jp"})
+	if upstreams.LeetCodeGraphQL != "https://leetcode.example.com/graphql" || upstreams.AtCoder != "https://atcoder.jp" {
+		t.Errorf("upstreams = %+v, want LeetCode overridden and AtCoder kept", upstreams)
+	}
+}
+
+func TestValidateReportsInvalidSettings(t *testing.T) {
+	chdir(t, t.TempDir())
+	t.Setenv("REFRESH_INTERVAL", "often")
+	t.Setenv("UPSTREAM_TIMEOUT_WAKATIME", "-")
+	t.Setenv("CACHE_MAX_ENTRIES", "-1")
+	t.Setenv("CODEWARS_URL", "codewars.com")
+	t.Setenv("GITHUB_INSTANCES", "corp,lab")
+	t.Setenv("GITHUB_CORP_TOKEN", "corp-token")
+	t.Setenv("GITHUB_LAB_API_URL", "ghe.lab.example.com/api/v3")
+	t.Setenv("GITHUB_API_URL", "https://ghe.example.com/rest")
+	t.Setenv("GITHUB_GRAPHQL_URL", "ghe.example.com/api/graphql")
+
+	env, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	err = env.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted invalid settings")
+	}
+	for _, key := range []string{"REFRESH_INTERVAL", "UPSTREAM_TIMEOUT_WAKATIME", "CACHE_MAX_ENTRIES", "CODEWARS_URL", "GITHUB_CORP_API_URL", "GITHUB_LAB_API_URL", "GITHUB_GRAPHQL_URL"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Validate error does not mention %s: %v", key, err)
+		}
+	}
+	if opts := env.RefreshOptions(); opts.Interval != time.Minute {
+		t.Errorf("refresh interval = %v, want the default kept", opts.Interval)
+	}
+	if instance, _ := env.GitHubInstance(""); instance.GraphQLURL != "" {
+		t.Errorf("default instance GraphQL URL = %q, want the invalid one left out", instance.GraphQLURL)
+	}
+	if instance, ok := env.GitHubInstance("lab"); ok {
+		t.Errorf("instance with an invalid API URL = %+v, want it rejected", instance)
+	}
+	if instance, ok := env.GitHubInstance("corp"); ok {
+		t.Errorf("instance without an API URL = %+v, want it rejected rather than sent to github.com", instance)
+	}
+}
+
+func TestCardCachePoliciesLeaveOutBounds(t *testing.T) {
+	chdir(t, t.TempDir())
+	t.Setenv("CARD_CACHE_STATS", "max-age=300, s-maxage=1800")
+	t.Setenv("CARD_CACHE_MIN_SECONDS", "30")
+	t.Setenv("CARD_CACHE_MAX_SECONDS", "3600")
+
+	env, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if policy := env.CardCachePolicy("stats", models.CachePolicy{StaleWhileRevalidate: 86400}); policy != (models.CachePolicy{MaxAge: 300, SMaxAge: 1800, StaleWhileRevalidate: 86400}) {
+		t.Errorf("stats policy = %+v, want max-age and s-maxage overridden", policy)
+	}
+	if minSeconds, maxSeconds := env.CardCacheBounds(); minSeconds != 30 || maxSeconds != 3600 {
+		t.Errorf("bounds = %d, %d, want 30, 3600", minSeconds, maxSeconds)
+	}
+	if len(env.cardPolicies) != 1 {
+		t.Errorf("card policies = %v, want only the stats card", env.cardPolicies)
+	}
+}
@@ -0,0 +1,24 @@
+package history
+
+import (
+	"my-realm/internal/models"
+	"time"
+)
+
+// DateFormat is how snapshot days are written, one snapshot per user a day.
+const DateFormat = "2006-01-02"
+
+// Store keeps a daily snapshot of each tracked user's metrics.
+type Store interface {
+	// Record saves snapshot, replacing the one taken the same day.
+	Record(snapshot models.Snapshot) error
+	// Series returns the values of metric recorded since the day of since,
+	// oldest first.
+	Series(provider, username, metric string, since time.Time) ([]models.HistoryPoint, error)
+	// Prune drops the snapshots taken before the day of before.
+	Prune(before time.Time) error
+}
+
+func day(t time.Time) string {
+	return t.UTC().Format(DateFormat)
+}
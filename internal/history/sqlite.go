@@ -0,0 +1,94 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"my-realm/internal/models"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLite keeps snapshots in a SQLite database file, one row per user, metric
+// and day, using a pure-Go driver so the server still builds without cgo.
+type SQLite struct {
+	db *sql.DB
+}
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS snapshots (
+	provider TEXT NOT NULL,
+	username TEXT NOT NULL,
+	day      TEXT NOT NULL,
+	metric   TEXT NOT NULL,
+	value    REAL NOT NULL,
+	PRIMARY KEY (provider, username, metric, day)
+)`
+
+// NewSQLite opens the database at path, creating it and its table if needed.
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening history database: %w", err)
+	}
+	// SQLite allows a single writer; sharing one connection avoids busy errors.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating history table: %w", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) Record(snapshot models.Snapshot) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error recording snapshot: %w", err)
+	}
+	defer tx.Rollback()
+
+	for metric, value := range snapshot.Metrics {
+		_, err := tx.Exec(`INSERT INTO snapshots (provider, username, day, metric, value)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (provider, username, metric, day) DO UPDATE SET value = excluded.value`,
+			snapshot.Provider, snapshot.Username, day(snapshot.TakenAt), metric, value)
+		if err != nil {
+			return fmt.Errorf("error recording snapshot: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error recording snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) Series(provider, username, metric string, since time.Time) ([]models.HistoryPoint, error) {
+	rows, err := s.db.Query(`SELECT day, value FROM snapshots
+		WHERE provider = ? AND username = ? AND metric = ? AND day >= ?
+		ORDER BY day`,
+		provider, username, metric, day(since))
+	if err != nil {
+		return nil, fmt.Errorf("error reading history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.HistoryPoint{}
+	for rows.Next() {
+		var point models.HistoryPoint
+		if err := rows.Scan(&point.Date, &point.Value); err != nil {
+			return nil, fmt.Errorf("error reading history: %w", err)
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history: %w", err)
+	}
+	return points, nil
+}
+
+func (s *SQLite) Prune(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM snapshots WHERE day < ?`, day(before)); err != nil {
+		return fmt.Errorf("error pruning history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package models
+
+type AtCoderStats struct {
+	Rating         int              `json:"rating"`
+	HighestRating  int              `json:"highestRating"`
+	RankColor      string           `json:"rankColor"`
+	RatedContests  int              `json:"ratedContests"`
+	AcceptedCount  int              `json:"acceptedCount"`
+	ContestHistory []AtCoderContest `json:"contestHistory"`
+}
+
+type AtCoderContest struct {
+	ContestName string `json:"contestName"`
+	Date        string `json:"date"`
+	Place       int    `json:"place"`
+	OldRating   int    `json:"oldRating"`
+	NewRating   int    `json:"newRating"`
+	Performance int    `json:"performance"`
+}
+
+// AtCoderHistoryEntry is one element of https://atcoder.jp/users/{user}/history/json.
+type AtCoderHistoryEntry struct {
+	IsRated           bool   `json:"IsRated"`
+	Place             int    `json:"Place"`
+	OldRating         int    `json:"OldRating"`
+	NewRating         int    `json:"NewRating"`
+	Performance       int    `json:"Performance"`
+	ContestScreenName string `json:"ContestScreenName"`
+	ContestName       string `json:"ContestName"`
+	EndTime           string `json:"EndTime"`
+}
+
+type AtCoderACRankResponse struct {
+	Count int `json:"count"`
+	Rank  int `json:"rank"`
+}
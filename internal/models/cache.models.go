@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// CacheEntry is what providers keep in the cache: the fetched value, when it
+// was fetched, and the validators the upstream sent with it, if any.
+type CacheEntry[T any] struct {
+	Value        T         `json:"value"`
+	Timestamp    time.Time `json:"timestamp"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+}
+
+// CacheInfo describes the cached value a provider answered with.
+type CacheInfo struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Stale     bool      `json:"stale"`
+}
+
+type CacheStats struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// WithHitRatio returns s with HitRatio worked out from its hits and misses.
+func (s CacheStats) WithHitRatio() CacheStats {
+	s.HitRatio = 0
+	if lookups := s.Hits + s.Misses; lookups > 0 {
+		s.HitRatio = float64(s.Hits) / float64(lookups)
+	}
+	return s
+}
+
+// RenderedCard is an SVG card kept for reuse, with the time its data was
+// fetched and the keys of the cache entries it came from.
+type RenderedCard struct {
+	SVG          string    `json:"svg"`
+	LastModified time.Time `json:"lastModified"`
+	Keys         []string  `json:"keys,omitempty"`
+}
+
+// CachePolicy is the Cache-Control sent with a card, in seconds.
+type CachePolicy struct {
+	MaxAge               int
+	SMaxAge              int
+	StaleWhileRevalidate int
+}
+
+// RefreshOptions configures the background refresh of popular cache entries.
+type RefreshOptions struct {
+	// Hottest is how many of the most requested entries are kept warm; zero
+	// turns the refresher off.
+	Hottest int
+	// Interval is how often entries are checked, and the window refreshes are
+	// spread over.
+	Interval time.Duration
+	// Concurrency caps the refreshes running at once.
+	Concurrency int
+	// GitHubReserve is the part of the GitHub rate limit left for requests.
+	GitHubReserve int
+}
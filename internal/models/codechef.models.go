@@ -0,0 +1,10 @@
+package models
+
+type CodeChefStats struct {
+	Rating         int `json:"rating"`
+	Stars          int `json:"stars"`
+	HighestRating  int `json:"highestRating"`
+	GlobalRank     int `json:"globalRank"`
+	CountryRank    int `json:"countryRank"`
+	ProblemsSolved int `json:"problemsSolved"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+type Feed struct {
+	Title string     `json:"title"`
+	Link  string     `json:"link,omitempty"`
+	Posts []FeedPost `json:"posts"`
+}
+
+type FeedPost struct {
+	Title              string    `json:"title"`
+	URL                string    `json:"url"`
+	PublishedAt        time.Time `json:"publishedAt"`
+	ReadingTimeMinutes int       `json:"readingTimeMinutes,omitempty"`
+	Reactions          *int      `json:"reactions,omitempty"`
+}
+
+// RSSDocument is the subset of RSS 2.0 used for the latest posts card.
+type RSSDocument struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Link  string `xml:"link"`
+		Items []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			PubDate     string `xml:"pubDate"`
+			Description string `xml:"description"`
+			Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// AtomDocument is the subset of Atom (RFC 4287) used for the latest posts card.
+type AtomDocument struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+	} `xml:"entry"`
+}
+
+type DevToArticle struct {
+	Title                string    `json:"title"`
+	URL                  string    `json:"url"`
+	PublishedAt          time.Time `json:"published_at"`
+	ReadingTimeMinutes   int       `json:"reading_time_minutes"`
+	PublicReactionsCount int       `json:"public_reactions_count"`
+}
+
+type HashnodeResponse struct {
+	Data struct {
+		User *struct {
+			Name  string `json:"name"`
+			Posts struct {
+				Nodes []struct {
+					Title             string    `json:"title"`
+					URL               string    `json:"url"`
+					PublishedAt       time.Time `json:"publishedAt"`
+					ReadTimeInMinutes int       `json:"readTimeInMinutes"`
+					ReactionCount     int       `json:"reactionCount"`
+				} `json:"nodes"`
+			} `json:"posts"`
+		} `json:"user"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors,omitempty"`
+}
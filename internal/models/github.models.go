@@ -2,9 +2,18 @@ package models
 
 import "time"
 
-type GitHubCache struct {
-	Stats     ProfileStats `json:"stats"`
-	Timestamp time.Time    `json:"timestamp"`
+// GitHubInstance is a GitHub host the stats can be fetched from, either
+// github.com or a GitHub Enterprise Server. Empty URLs fall back to github.com.
+// Requests are spread over Tokens by the quota each has left.
+type GitHubInstance struct {
+	Name       string
+	APIURL     string
+	GraphQLURL string
+	Tokens     []string
+}
+
+type GitHubRepository struct {
+	Language string `json:"language"`
 }
 
 type ProfileStats struct {
@@ -12,6 +21,8 @@ type ProfileStats struct {
 	TotalCommits       int               `json:"total_commits"`
 	TotalPRs           int               `json:"total_pull_requests"`
 	TotalIssues        int               `json:"total_issues"`
+	Followers          int               `json:"followers"`
+	Stars              int               `json:"stars"`
 	ContributionsByDay []DayContribution `json:"contributions_by_day"`
 }
 
@@ -39,6 +50,35 @@ type GraphQLResponse struct {
 					} `json:"weeks"`
 				} `json:"contributionCalendar"`
 			} `json:"contributionsCollection"`
+			Followers struct {
+				TotalCount int `json:"totalCount"`
+			} `json:"followers"`
+			Repositories struct {
+				Nodes []struct {
+					StargazerCount int `json:"stargazerCount"`
+				} `json:"nodes"`
+			} `json:"repositories"`
 		} `json:"user"`
+		RateLimit *struct {
+			Remaining int       `json:"remaining"`
+			ResetAt   time.Time `json:"resetAt"`
+			Cost      int       `json:"cost"`
+		} `json:"rateLimit"`
 	} `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors,omitempty"`
+}
+
+// RateLimit is the quota an upstream reported last. Remaining is -1 until one
+// is reported.
+type RateLimit struct {
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// Known reports whether the upstream has reported its quota.
+func (r RateLimit) Known() bool {
+	return r.Remaining >= 0
 }
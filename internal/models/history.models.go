@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Snapshot is the metrics of a user on a provider at one point in time.
+type Snapshot struct {
+	Provider string             `json:"provider"`
+	Username string             `json:"username"`
+	TakenAt  time.Time          `json:"takenAt"`
+	Metrics  map[string]float64 `json:"metrics"`
+}
+
+// HistoryPoint is the value a metric had on a day, formatted as 2006-01-02.
+type HistoryPoint struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+}
+
+// HistorySeries is the daily values of one metric of a user, oldest first.
+type HistorySeries struct {
+	Provider string         `json:"provider"`
+	Username string         `json:"username"`
+	Metric   string         `json:"metric"`
+	Points   []HistoryPoint `json:"points"`
+}
+
+// TrackedUser is a user whose metrics are snapshotted every day.
+type TrackedUser struct {
+	Provider string
+	Username string
+}
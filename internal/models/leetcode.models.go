@@ -1,12 +1,5 @@
 package models
 
-import "time"
-
-type LeetCodeCache struct {
-	Stats     *LeetCodeStats `json:"stats"`
-	Timestamp time.Time      `json:"timestamp"`
-}
-
 type LeetCodeStats struct {
 	TotalSolved        int     `json:"totalSolved"`
 	TotalQuestions     int     `json:"totalQuestions"`
@@ -16,6 +9,7 @@ type LeetCodeStats struct {
 	AcceptanceRate     float64 `json:"acceptanceRate"`
 	Ranking            int     `json:"ranking"`
 	ContributionPoints int     `json:"contributionPoints"`
+	ContestRating      float64 `json:"contestRating"`
 }
 
 type LeetCodeSubmissionStats struct {
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// PackageStats describes a published library. Download counts are nil when
+// the registry does not publish them (e.g. weekly downloads on crates.io, or
+// anything on the Go module proxy).
+type PackageStats struct {
+	Registry        string    `json:"registry"`
+	Name            string    `json:"name"`
+	LatestVersion   string    `json:"latestVersion"`
+	PublishedAt     time.Time `json:"publishedAt"`
+	WeeklyDownloads *int64    `json:"weeklyDownloads,omitempty"`
+	TotalDownloads  *int64    `json:"totalDownloads,omitempty"`
+}
+
+type NpmPackageResponse struct {
+	DistTags struct {
+		Latest string `json:"latest"`
+	} `json:"dist-tags"`
+	Time map[string]time.Time `json:"time"`
+}
+
+type NpmDownloadsResponse struct {
+	Downloads int64 `json:"downloads"`
+}
+
+type PyPIPackageResponse struct {
+	Info struct {
+		Version string `json:"version"`
+	} `json:"info"`
+	URLs []struct {
+		UploadTime time.Time `json:"upload_time_iso_8601"`
+	} `json:"urls"`
+}
+
+type PyPIStatsResponse struct {
+	Data struct {
+		LastWeek int64 `json:"last_week"`
+	} `json:"data"`
+}
+
+type CratesIOResponse struct {
+	Crate struct {
+		MaxStableVersion string `json:"max_stable_version"`
+		NewestVersion    string `json:"newest_version"`
+		Downloads        int64  `json:"downloads"`
+	} `json:"crate"`
+	Versions []struct {
+		Num       string    `json:"num"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"versions"`
+}
+
+type GoProxyLatestResponse struct {
+	Version string    `json:"Version"`
+	Time    time.Time `json:"Time"`
+}
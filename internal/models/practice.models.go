@@ -0,0 +1,64 @@
+package models
+
+type PracticeStats struct {
+	Platform       string             `json:"platform"`
+	Username       string             `json:"username"`
+	Rank           string             `json:"rank,omitempty"`
+	Score          int                `json:"score"`
+	ScoreLabel     string             `json:"scoreLabel"`
+	TotalCompleted int                `json:"totalCompleted"`
+	Languages      []PracticeLanguage `json:"languages"`
+}
+
+type PracticeLanguage struct {
+	Name      string `json:"name"`
+	Completed int    `json:"completed"`
+	Rank      string `json:"rank,omitempty"`
+}
+
+type ExercismProfileResponse struct {
+	Profile struct {
+		Handle     string `json:"handle"`
+		Reputation string `json:"reputation"`
+	} `json:"profile"`
+}
+
+type ExercismSolutionsResponse struct {
+	Results []struct {
+		Track struct {
+			Slug  string `json:"slug"`
+			Title string `json:"title"`
+		} `json:"track"`
+	} `json:"results"`
+	Meta struct {
+		CurrentPage int `json:"current_page"`
+		TotalPages  int `json:"total_pages"`
+	} `json:"meta"`
+}
+
+type CodewarsUserResponse struct {
+	Username string `json:"username"`
+	Honor    int    `json:"honor"`
+	Ranks    struct {
+		Overall   CodewarsRank            `json:"overall"`
+		Languages map[string]CodewarsRank `json:"languages"`
+	} `json:"ranks"`
+	CodeChallenges struct {
+		TotalCompleted int `json:"totalCompleted"`
+	} `json:"codeChallenges"`
+}
+
+type CodewarsRank struct {
+	Rank  int    `json:"rank"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Score int    `json:"score"`
+}
+
+type CodewarsCompletedResponse struct {
+	TotalPages int `json:"totalPages"`
+	TotalItems int `json:"totalItems"`
+	Data       []struct {
+		CompletedLanguages []string `json:"completedLanguages"`
+	} `json:"data"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "encoding/json"
+
+type StackExchangeStats struct {
+	DisplayName         string              `json:"displayName"`
+	Site                string              `json:"site"`
+	Reputation          int                 `json:"reputation"`
+	Badges              StackExchangeBadges `json:"badges"`
+	AnswerCount         int                 `json:"answerCount"`
+	QuestionCount       int                 `json:"questionCount"`
+	AcceptedAnswerRatio float64             `json:"acceptedAnswerRatio"`
+	TopTags             []StackExchangeTag  `json:"topTags"`
+}
+
+type StackExchangeBadges struct {
+	Gold   int `json:"gold"`
+	Silver int `json:"silver"`
+	Bronze int `json:"bronze"`
+}
+
+type StackExchangeTag struct {
+	Name          string `json:"name"`
+	AnswerCount   int    `json:"answerCount"`
+	AnswerScore   int    `json:"answerScore"`
+	QuestionCount int    `json:"questionCount"`
+}
+
+// StackExchangeResponse is the common wrapper around every Stack Exchange API
+// response. Backoff, when set, is the number of seconds to wait before calling
+// the same method again.
+type StackExchangeResponse struct {
+	Items          json.RawMessage `json:"items"`
+	Total          int             `json:"total"`
+	HasMore        bool            `json:"has_more"`
+	Backoff        int             `json:"backoff"`
+	QuotaRemaining int             `json:"quota_remaining"`
+	QuotaMax       int             `json:"quota_max"`
+	ErrorID        int             `json:"error_id"`
+	ErrorName      string          `json:"error_name"`
+	ErrorMessage   string          `json:"error_message"`
+}
+
+type StackExchangeUser struct {
+	DisplayName string              `json:"display_name"`
+	Reputation  int                 `json:"reputation"`
+	BadgeCounts StackExchangeBadges `json:"badge_counts"`
+}
+
+type StackExchangeAnswer struct {
+	IsAccepted bool `json:"is_accepted"`
+}
+
+type StackExchangeTopTag struct {
+	TagName       string `json:"tag_name"`
+	AnswerCount   int    `json:"answer_count"`
+	AnswerScore   int    `json:"answer_score"`
+	QuestionCount int    `json:"question_count"`
+}
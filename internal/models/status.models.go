@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// RateLimitStatus is how much of their quotas the upstreams have left and how
+// well the caches in front of them are doing.
+type RateLimitStatus struct {
+	GitHub    []TokenStatus             `json:"github"`
+	Upstreams map[string]UpstreamStatus `json:"upstreams"`
+	Cards     map[string]CacheStats     `json:"cards"`
+}
+
+// TokenStatus is the quota of one GitHub token for one resource, "core" for
+// REST calls or "graphql". Remaining is -1 until GitHub reports it.
+type TokenStatus struct {
+	Instance    string     `json:"instance"`
+	Token       string     `json:"token"`
+	Resource    string     `json:"resource"`
+	Remaining   int        `json:"remaining"`
+	Reset       *time.Time `json:"reset,omitempty"`
+	Requests    int64      `json:"requests"`
+	PausedUntil *time.Time `json:"pausedUntil,omitempty"`
+}
+
+// UpstreamStatus counts the lookups of an upstream's cached data and the
+// fetches that reached it in the current hour. Quota is null for upstreams
+// that don't report one; GitHub's are listed per token instead. Circuit is
+// "open" or "half-open" while calls to the upstream are being held back.
+type UpstreamStatus struct {
+	Cache   CacheStats     `json:"cache"`
+	Fetches int64          `json:"fetchesThisHour"`
+	Quota   *UpstreamQuota `json:"quota"`
+	Circuit string         `json:"circuit,omitempty"`
+}
+
+// UpstreamQuota is the quota an upstream last reported, e.g. Stack Exchange's
+// daily request quota.
+type UpstreamQuota struct {
+	Remaining int       `json:"remaining"`
+	Max       int       `json:"max"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
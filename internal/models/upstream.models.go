@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Upstreams holds the base URL of every API the providers talk to. Paths are
+// appended to these, so a test can point all of them at one local server.
+type Upstreams struct {
+	GitHubAPI       string
+	GitHubGraphQL   string
+	LeetCodeGraphQL string
+	AtCoder         string
+	AtCoderProblems string
+	CodeChef        string
+	StackExchange   string
+	WakaTime        string
+	Exercism        string
+	Codewars        string
+	DevTo           string
+	Hashnode        string
+	NpmRegistry     string
+	NpmDownloads    string
+	PyPI            string
+	PyPIStats       string
+	CratesIO        string
+	GoProxy         string
+}
+
+// TransportOptions configures how upstream calls are retried and when a
+// failing upstream is left alone for a while.
+type TransportOptions struct {
+	// MaxRetries is how many times a failed read is sent again; zero turns
+	// retries off.
+	MaxRetries int
+	// BaseBackoff is the wait before the first retry, doubled for each one
+	// after it up to MaxBackoff. A Retry-After longer than MaxBackoff is not
+	// waited out.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BreakerThreshold is how many calls in a row an upstream may fail before
+	// its circuit opens; zero turns the breaker off.
+	BreakerThreshold int
+	// BreakerCooldown is how long an open circuit fails calls right away
+	// before a single trial call is let through.
+	BreakerCooldown time.Duration
+}
@@ -0,0 +1,37 @@
+package models
+
+type WakaTimeStats struct {
+	Range          string         `json:"range"`
+	TotalSeconds   float64        `json:"totalSeconds"`
+	AllTimeSeconds float64        `json:"allTimeSeconds"`
+	Languages      []WakaTimeItem `json:"languages"`
+	Editors        []WakaTimeItem `json:"editors"`
+	Projects       []WakaTimeItem `json:"projects"`
+}
+
+type WakaTimeItem struct {
+	Name         string  `json:"name"`
+	TotalSeconds float64 `json:"totalSeconds"`
+	Percent      float64 `json:"percent"`
+}
+
+type WakaTimeStatsResponse struct {
+	Data struct {
+		TotalSeconds float64             `json:"total_seconds"`
+		Languages    []WakaTimeStatsItem `json:"languages"`
+		Editors      []WakaTimeStatsItem `json:"editors"`
+		Projects     []WakaTimeStatsItem `json:"projects"`
+	} `json:"data"`
+}
+
+type WakaTimeStatsItem struct {
+	Name         string  `json:"name"`
+	TotalSeconds float64 `json:"total_seconds"`
+	Percent      float64 `json:"percent"`
+}
+
+type WakaTimeAllTimeResponse struct {
+	Data struct {
+		TotalSeconds float64 `json:"total_seconds"`
+	} `json:"data"`
+}
@@ -0,0 +1,288 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"my-realm/internal/models"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+type atcoderRank struct {
+	Name   string
+	Min    int
+	Ceil   int
+	Accent string
+}
+
+// atcoderRanks lists AtCoder's rating colors from highest to lowest. Ceil is
+// the rating at which the next color starts and is used to size progress bars.
+var atcoderRanks = []atcoderRank{
+	{Name: "red", Min: 2800, Ceil: 3200, Accent: "rgb(255, 0, 0)"},
+	{Name: "orange", Min: 2400, Ceil: 2800, Accent: "rgb(255, 128, 0)"},
+	{Name: "yellow", Min: 2000, Ceil: 2400, Accent: "rgb(192, 192, 0)"},
+	{Name: "blue", Min: 1600, Ceil: 2000, Accent: "rgb(0, 0, 255)"},
+	{Name: "cyan", Min: 1200, Ceil: 1600, Accent: "rgb(0, 192, 192)"},
+	{Name: "green", Min: 800, Ceil: 1200, Accent: "rgb(0, 128, 0)"},
+	{Name: "brown", Min: 400, Ceil: 800, Accent: "rgb(128, 64, 0)"},
+	{Name: "gray", Min: 0, Ceil: 400, Accent: "rgb(128, 128, 128)"},
+}
+
+var errAtCoderProfileNotFound = errors.New("atcoder profile data not found")
+
+var (
+	atcoderRatingPattern        = regexp.MustCompile(`(?s)<th[^>]*>\s*Rating\s*</th>\s*<td[^>]*>.*?(\d+)`)
+	atcoderHighestRatingPattern = regexp.MustCompile(`(?s)<th[^>]*>\s*Highest Rating\s*</th>\s*<td[^>]*>.*?(\d+)`)
+	atcoderRatedMatchesPattern  = regexp.MustCompile(`(?s)<th[^>]*>\s*Rated Matches.*?</th>\s*<td[^>]*>\s*(\d+)`)
+)
+
+func FetchAtCoderStats(ctx context.Context, username string) (*models.AtCoderStats, models.CacheInfo, error) {
+	return cachedFetch(ctx, "atcoder:"+username, CacheTTLs["atcoder"], func(ctx context.Context) (*models.AtCoderStats, error) {
+		return fetchAtCoderStats(ctx, username)
+	})
+}
+
+func fetchAtCoderStats(ctx context.Context, username string) (*models.AtCoderStats, error) {
+	stats, err := fetchAtCoderHistory(ctx, username)
+	if err != nil {
+		stats, err = fetchAtCoderProfilePage(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if count, err := fetchAtCoderAcceptedCount(ctx, username); err == nil {
+		stats.AcceptedCount = count
+	}
+	stats.RankColor = atcoderRankFor(stats.Rating).Name
+
+	return stats, nil
+}
+
+func fetchAtCoderHistory(ctx context.Context, username string) (*models.AtCoderStats, error) {
+	resp, err := httpGet(ctx, fmt.Sprintf("%s/users/%s/history/json", upstreams.AtCoder, url.PathEscape(username)))
+	if err != nil {
+		return nil, requestError("AtCoder", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("AtCoder", resp)
+	}
+
+	var history []models.AtCoderHistoryEntry
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	stats := &models.AtCoderStats{
+		ContestHistory: []models.AtCoderContest{},
+	}
+	for _, entry := range history {
+		if !entry.IsRated {
+			continue
+		}
+
+		stats.RatedContests++
+		stats.Rating = entry.NewRating
+		if entry.NewRating > stats.HighestRating {
+			stats.HighestRating = entry.NewRating
+		}
+
+		date := entry.EndTime
+		if len(date) >= len("2006-01-02") {
+			date = date[:len("2006-01-02")]
+		}
+		stats.ContestHistory = append(stats.ContestHistory, models.AtCoderContest{
+			ContestName: entry.ContestName,
+			Date:        date,
+			Place:       entry.Place,
+			OldRating:   entry.OldRating,
+			NewRating:   entry.NewRating,
+			Performance: entry.Performance,
+		})
+	}
+
+	return stats, nil
+}
+
+// fetchAtCoderProfilePage scrapes rating data from the user's profile page and
+// is used when the history JSON is unavailable. It carries no contest history.
+func fetchAtCoderProfilePage(ctx context.Context, username string) (*models.AtCoderStats, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/users/%s", upstreams.AtCoder, url.PathEscape(username)), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, requestError("AtCoder", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("AtCoder", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	return parseAtCoderProfile(body)
+}
+
+func parseAtCoderProfile(body []byte) (*models.AtCoderStats, error) {
+	if !atcoderRatingPattern.Match(body) && !atcoderRatedMatchesPattern.Match(body) {
+		return nil, errAtCoderProfileNotFound
+	}
+
+	stats := &models.AtCoderStats{
+		ContestHistory: []models.AtCoderContest{},
+	}
+	stats.Rating = matchInt(atcoderRatingPattern, body)
+	stats.HighestRating = matchInt(atcoderHighestRatingPattern, body)
+	stats.RatedContests = matchInt(atcoderRatedMatchesPattern, body)
+
+	return stats, nil
+}
+
+// fetchAtCoderAcceptedCount asks AtCoder Problems for the unique AC count, which
+// AtCoder itself does not publish.
+func fetchAtCoderAcceptedCount(ctx context.Context, username string) (int, error) {
+	resp, err := httpGet(ctx, upstreams.AtCoderProblems+"/user/ac_rank?user="+url.QueryEscape(username))
+	if err != nil {
+		return 0, requestError("AtCoder Problems", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, statusError("AtCoder Problems", resp)
+	}
+
+	var result models.AtCoderACRankResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return result.Count, nil
+}
+
+func atcoderRankFor(rating int) atcoderRank {
+	for _, rank := range atcoderRanks {
+		if rating >= rank.Min {
+			return rank
+		}
+	}
+	return atcoderRanks[len(atcoderRanks)-1]
+}
+
+func matchInt(pattern *regexp.Regexp, body []byte) int {
+	match := pattern.FindSubmatch(body)
+	if match == nil {
+		return 0
+	}
+	value, err := strconv.Atoi(string(match[1]))
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func GenerateAtCoderStatsSVG(stats *models.AtCoderStats, username, color, background string) string {
+	themeColor, bgColor, barBgColor := resolveTheme(color, background)
+
+	current := atcoderRankFor(stats.Rating)
+	highest := atcoderRankFor(stats.HighestRating)
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="330" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .stat {
+                font: 500 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .stat-title {
+                font: 400 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.8;
+            }
+            .rank {
+                font: 700 24px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                opacity: 0.9;
+            }
+            .progress-bar-bg {
+                fill: %s;
+                opacity: 0.2;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="330"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(25, 35)">
+            <text x="0" y="0" class="title">@%s's AtCoder Stats</text>
+
+            <g transform="translate(0, 55)">
+                <text class="stat-title">Rating</text>
+                <text x="0" y="25" class="rank" style="fill: %s">%d</text>
+                <text x="430" y="25" class="stat" text-anchor="end" style="fill: %s">%s</text>
+            </g>
+
+            <g transform="translate(0, 120)">
+                %s
+            </g>
+
+            <g transform="translate(0, 240)">
+                <text class="stat-title">Rated Contests</text>
+                <text x="430" y="0" class="stat" text-anchor="end">%d</text>
+
+                <text y="30" class="stat-title">Accepted Problems</text>
+                <text x="430" y="30" class="stat" text-anchor="end">%d</text>
+            </g>
+        </g>
+    </svg>`
+
+	ratingBars := progressBar(0, "Rating", fmt.Sprintf("%d / %d", stats.Rating, current.Ceil), current.Accent,
+		float64(stats.Rating)/float64(current.Ceil)) +
+		progressBar(45, "Highest Rating", fmt.Sprintf("%d / %d", stats.HighestRating, highest.Ceil), highest.Accent,
+			float64(stats.HighestRating)/float64(highest.Ceil))
+
+	return fmt.Sprintf(svgTemplate,
+		themeColor,
+		themeColor,
+		themeColor,
+		barBgColor,
+		bgColor,
+		themeColor,
+		html.EscapeString(username),
+		current.Accent,
+		stats.Rating,
+		current.Accent,
+		current.Name,
+		ratingBars,
+		stats.RatedContests,
+		stats.AcceptedCount)
+}
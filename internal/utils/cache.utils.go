@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"my-realm/internal/cache"
+	"my-realm/internal/models"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var store cache.Cache = cache.NewMemory(cache.DefaultMaxEntries)
+
+// SetCache replaces the cache behind every provider and rendered card. It is
+// meant to be called before the server starts handling requests.
+func SetCache(c cache.Cache) {
+	store = c
+}
+
+// loadCached decodes the entry stored under key into target.
+func loadCached(key string, target any) bool {
+	data, ok := store.Get(key)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, target) == nil
+}
+
+// storeCached encodes value under key for ttl. A failed write only costs a
+// refetch later, so it is logged rather than returned.
+func storeCached(key string, value any, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("error encoding cache entry %s: %v", key, err)
+		return
+	}
+	if err := store.Set(key, data, ttl); err != nil {
+		log.Printf("error writing cache entry %s: %v", key, err)
+	}
+}
+
+// flight is an upstream fetch in progress, shared by every caller asking for
+// the same key until it completes. It is cancelled once none of the callers
+// waiting on it are left.
+type flight struct {
+	done    chan struct{}
+	value   any
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+var (
+	flights      = make(map[string]*flight)
+	flightsMutex sync.Mutex
+)
+
+// MaxStaleness is how long past its TTL a cached value is still served, while
+// it is refreshed in the background or while its upstream is failing.
+var MaxStaleness = 24 * time.Hour
+
+// cachedFetch returns the value cached under key. On a miss it calls fetch
+// and caches the result for ttl, with concurrent misses for the same key
+// waiting on a single call instead of each reaching the upstream. fetch is
+// given a context that ends at the upstream's timeout, or once ctx and the
+// contexts of every other caller waiting on it are done.
+//
+// A value older than ttl but within MaxStaleness is returned as stale right
+// away while a refresh runs in the background; if the refresh fails, the
+// stale value keeps being served until MaxStaleness runs out.
+func cachedFetch[T any](ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) (T, models.CacheInfo, error) {
+	return cachedConditionalFetch(ctx, key, ttl, func(ctx context.Context, _ models.CacheEntry[T]) (models.CacheEntry[T], error) {
+		value, err := fetch(ctx)
+		return models.CacheEntry[T]{Value: value}, err
+	})
+}
+
+// cachedConditionalFetch is cachedFetch for upstreams that support conditional
+// requests. fetch is given the entry cached so far, zero on a miss, so it can
+// send its validators and return it unchanged when the upstream answers 304
+// Not Modified; either way the entry is stored as fetched now.
+func cachedConditionalFetch[T any](ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context, cached models.CacheEntry[T]) (models.CacheEntry[T], error)) (T, models.CacheInfo, error) {
+	trackRequest(ctx, key, ttl, func(ctx context.Context, minAge time.Duration) error {
+		_, err := refreshCached(ctx, key, minAge, ttl, fetch)
+		return err
+	})
+
+	var cached models.CacheEntry[T]
+	found := loadCached(key, &cached)
+	countCacheLookup(key, found)
+	if found {
+		if time.Since(cached.Timestamp) < ttl {
+			return cached.Value, models.CacheInfo{FetchedAt: cached.Timestamp}, nil
+		}
+
+		// The refresh outlives the request that noticed the entry is stale.
+		go func() {
+			if _, err := refreshCached(context.WithoutCancel(ctx), key, ttl, ttl, fetch); err != nil {
+				log.Printf("error refreshing %s, serving stale data: %v", key, err)
+			}
+		}()
+		return cached.Value, models.CacheInfo{FetchedAt: cached.Timestamp, Stale: true}, nil
+	}
+
+	entry, err := refreshCached(ctx, key, ttl, ttl, fetch)
+	if err != nil {
+		return entry.Value, models.CacheInfo{}, err
+	}
+	return entry.Value, models.CacheInfo{FetchedAt: entry.Timestamp}, nil
+}
+
+// refreshCached calls fetch, once per key at a time, and caches its result
+// unless the cached entry is younger than minAge. fetch gets as long as
+// upstreamTimeout allows for the upstream key belongs to, and its calls go
+// through that upstream's circuit.
+func refreshCached[T any](ctx context.Context, key string, minAge, ttl time.Duration, fetch func(ctx context.Context, cached models.CacheEntry[T]) (models.CacheEntry[T], error)) (models.CacheEntry[T], error) {
+	return coalesce(ctx, key, func(ctx context.Context) (models.CacheEntry[T], error) {
+		// An earlier flight may have refreshed the entry since it was loaded.
+		var cached models.CacheEntry[T]
+		if loadCached(key, &cached) && time.Since(cached.Timestamp) < minAge {
+			return cached, nil
+		}
+
+		ctx, cancel := context.WithTimeout(withUpstream(ctx, upstreamName(key)), upstreamTimeout(key))
+		defer cancel()
+
+		countFetch(key)
+		entry, err := fetch(ctx, cached)
+		if err != nil {
+			return models.CacheEntry[T]{}, err
+		}
+		entry.Timestamp = time.Now()
+		storeCached(key, entry, ttl+MaxStaleness)
+		return entry, nil
+	})
+}
+
+// coalesce calls fn unless a call for key is already in flight, in which
+// case it waits for that call and returns its result. The call runs on its
+// own, with the values of ctx but not its cancellation: a caller whose ctx
+// is done stops waiting, and the call is cancelled once the last caller has
+// stopped waiting.
+func coalesce[T any](ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	flightsMutex.Lock()
+	current, exists := flights[key]
+	if !exists {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		current = &flight{done: make(chan struct{}), cancel: cancel}
+		flights[key] = current
+
+		go func() {
+			defer func() {
+				// A panicking fetch fails its waiters instead of taking the
+				// server down, as a handler's panic would not.
+				if r := recover(); r != nil {
+					log.Printf("panic fetching %s: %v\n%s", key, r, debug.Stack())
+					current.err = fmt.Errorf("fetching %s panicked: %v", key, r)
+				}
+
+				flightsMutex.Lock()
+				if flights[key] == current {
+					delete(flights, key)
+				}
+				flightsMutex.Unlock()
+				cancel()
+				close(current.done)
+			}()
+
+			value, err := fn(callCtx)
+			current.value, current.err = value, err
+		}()
+	}
+	current.waiters++
+	flightsMutex.Unlock()
+
+	select {
+	case <-current.done:
+		value, _ := current.value.(T)
+		return value, current.err
+	case <-ctx.Done():
+		flightsMutex.Lock()
+		current.waiters--
+		if current.waiters == 0 {
+			// Later callers start a call of their own rather than join
+			// this one as it is being cancelled.
+			if flights[key] == current {
+				delete(flights, key)
+			}
+			current.cancel()
+		}
+		flightsMutex.Unlock()
+
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// CacheTTLs is how long each provider's data is fresh for, after which it is
+// served stale while it is refreshed.
+var CacheTTLs = map[string]time.Duration{
+	"github":        10 * time.Minute,
+	"leetcode":      10 * time.Minute,
+	"atcoder":       10 * time.Minute,
+	"codechef":      10 * time.Minute,
+	"stackexchange": 30 * time.Minute,
+	"wakatime":      30 * time.Minute,
+	"practice":      30 * time.Minute,
+	"feed":          30 * time.Minute,
+	"package":       1 * time.Hour,
+}
+
+// CardTTL is how long a rendered card is reused for the same parameters.
+var CardTTL = 1 * time.Minute
+
+var (
+	cardStats      = make(map[string]*models.CacheStats)
+	cardStatsMutex sync.Mutex
+)
+
+// LoadCachedCard returns the card endpoint rendered for key, counting the
+// lookup as a hit or miss of endpoint.
+func LoadCachedCard(endpoint, key string) (models.RenderedCard, bool) {
+	var card models.RenderedCard
+	ok := loadCached("card:"+key, &card)
+
+	cardStatsMutex.Lock()
+	stats, exists := cardStats[endpoint]
+	if !exists {
+		stats = &models.CacheStats{}
+		cardStats[endpoint] = stats
+	}
+	if ok {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+	cardStatsMutex.Unlock()
+
+	return card, ok
+}
+
+// StoreCachedCard keeps a rendered card under key for CardTTL.
+func StoreCachedCard(key string, card models.RenderedCard) {
+	storeCached("card:"+key, card, CardTTL)
+}
+
+// CardCacheStats returns the rendered card hits and misses per endpoint.
+func CardCacheStats() map[string]models.CacheStats {
+	cardStatsMutex.Lock()
+	defer cardStatsMutex.Unlock()
+
+	result := make(map[string]models.CacheStats, len(cardStats))
+	for endpoint, stats := range cardStats {
+		result[endpoint] = stats.WithHitRatio()
+	}
+	return result
+}
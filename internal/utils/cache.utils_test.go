@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"my-realm/internal/cache"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func useTestCache(t *testing.T) {
+	t.Helper()
+
+	previous := store
+	SetCache(cache.NewMemory(100))
+	t.Cleanup(func() { SetCache(previous) })
+}
+
+func TestCachedFetchServesStaleWhileRevalidating(t *testing.T) {
+	useTestCache(t)
+
+	var calls atomic.Int64
+	refreshed := make(chan struct{}, 1)
+	fetch := func(context.Context) (int, error) {
+		n := calls.Add(1)
+		if n > 1 {
+			defer func() { refreshed <- struct{}{} }()
+		}
+		return int(n), nil
+	}
+
+	const ttl = 20 * time.Millisecond
+	value, info, err := cachedFetch(context.Background(), "test:swr", ttl, fetch)
+	if err != nil || value != 1 || info.Stale {
+		t.Fatalf("first fetch = %d, %+v, %v; want 1, fresh", value, info, err)
+	}
+
+	time.Sleep(2 * ttl)
+
+	value, info, err = cachedFetch(context.Background(), "test:swr", ttl, fetch)
+	if err != nil || value != 1 || !info.Stale {
+		t.Fatalf("expired fetch = %d, %+v, %v; want stale 1", value, info, err)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale entry was not refreshed in the background")
+	}
+
+	// The refresh stores its result just after fetch returns.
+	deadline := time.Now().Add(time.Second)
+	for {
+		value, info, err = cachedFetch(context.Background(), "test:swr", ttl, fetch)
+		if err == nil && value == 2 && !info.Stale {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("after refresh = %d, %+v, %v; want fresh 2", value, info, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCachedFetchServesStaleOnError(t *testing.T) {
+	useTestCache(t)
+
+	errUpstream := errors.New("upstream is down")
+	var failing atomic.Bool
+	fetch := func(context.Context) (string, error) {
+		if failing.Load() {
+			return "", errUpstream
+		}
+		return "cached", nil
+	}
+
+	const ttl = 10 * time.Millisecond
+	if _, _, err := cachedFetch(context.Background(), "test:stale-on-error", ttl, fetch); err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+
+	failing.Store(true)
+	time.Sleep(2 * ttl)
+
+	for i := 0; i < 3; i++ {
+		value, info, err := cachedFetch(context.Background(), "test:stale-on-error", ttl, fetch)
+		if err != nil || value != "cached" || !info.Stale {
+			t.Fatalf("fetch %d while failing = %q, %+v, %v; want stale value", i, value, info, err)
+		}
+		time.Sleep(ttl)
+	}
+
+	if _, _, err := cachedFetch(context.Background(), "test:never-cached", ttl, fetch); !errors.Is(err, errUpstream) {
+		t.Fatalf("uncached fetch while failing: err = %v, want %v", err, errUpstream)
+	}
+}
+
+func TestCachedFetchDropsEntriesPastMaxStaleness(t *testing.T) {
+	useTestCache(t)
+
+	previous := MaxStaleness
+	MaxStaleness = 10 * time.Millisecond
+	t.Cleanup(func() { MaxStaleness = previous })
+
+	errUpstream := errors.New("upstream is down")
+	var failing atomic.Bool
+	fetch := func(context.Context) (string, error) {
+		if failing.Load() {
+			return "", errUpstream
+		}
+		return "cached", nil
+	}
+
+	const ttl = 10 * time.Millisecond
+	if _, _, err := cachedFetch(context.Background(), "test:max-staleness", ttl, fetch); err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+
+	failing.Store(true)
+	time.Sleep(3 * (ttl + MaxStaleness))
+
+	if _, _, err := cachedFetch(context.Background(), "test:max-staleness", ttl, fetch); !errors.Is(err, errUpstream) {
+		t.Fatalf("fetch past max staleness: err = %v, want %v", err, errUpstream)
+	}
+}
+
+func TestCoalesceCancelsOnceEveryCallerLeaves(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	fn := func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, ctx.Err()
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := coalesce(firstCtx, "test:cancel", fn)
+		firstDone <- err
+	}()
+	<-started
+
+	secondDone := make(chan error, 1)
+	go func() {
+		_, err := coalesce(secondCtx, "test:cancel", func(context.Context) (int, error) {
+			t.Error("second caller started its own call instead of waiting")
+			return 0, nil
+		})
+		secondDone <- err
+	}()
+	// Give the second caller time to join the call in flight.
+	time.Sleep(10 * time.Millisecond)
+
+	cancelFirst()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller: err = %v, want context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("call was cancelled while the second caller still waited on it")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	if err := <-secondDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("second caller: err = %v, want context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("call was not cancelled after every caller left")
+	}
+}
+
+func TestCoalesceRecoversFromPanics(t *testing.T) {
+	_, err := coalesce(context.Background(), "test:panic", func(context.Context) (int, error) {
+		panic("malformed upstream response")
+	})
+	if err == nil {
+		t.Fatal("panicking call returned no error")
+	}
+
+	value, err := coalesce(context.Background(), "test:panic", func(context.Context) (int, error) {
+		return 1, nil
+	})
+	if err != nil || value != 1 {
+		t.Errorf("call after a panic = %d, %v, want a fresh call", value, err)
+	}
+}
+
+func TestCachedFetchTimesOutPerUpstream(t *testing.T) {
+	useTestCache(t)
+
+	UpstreamTimeouts["test"] = 20 * time.Millisecond
+	t.Cleanup(func() { delete(UpstreamTimeouts, "test") })
+
+	start := time.Now()
+	_, _, err := cachedFetch(context.Background(), "test:slow", time.Minute, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, requestError("Test", ctx.Err())
+	})
+	if !errors.Is(err, ErrUpstreamTimeout) {
+		t.Fatalf("err = %v, want ErrUpstreamTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > DefaultUpstreamTimeout/2 {
+		t.Errorf("fetch took %s, want about the upstream's 20ms", elapsed)
+	}
+}
@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"my-realm/internal/models"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var errCodeChefProfileNotFound = errors.New("codechef profile data not found")
+
+// The CodeChef profile page has no API and its markup changes every so often,
+// so every field is looked up through several patterns: first against the raw
+// HTML by class name, then against the page's visible text by label.
+var (
+	codechefScriptPattern = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	codechefTagPattern    = regexp.MustCompile(`<[^>]+>`)
+	codechefSpacePattern  = regexp.MustCompile(`\s+`)
+
+	codechefRatingClassPattern = regexp.MustCompile(`(?is)class=["'][^"']*\brating-number\b[^"']*["'][^>]*>\s*(?:<[^>]+>\s*)*([\d,]+)`)
+	codechefStarBlockPattern   = regexp.MustCompile(`(?is)class=["'][^"']*\brating-star\b[^"']*["'][^>]*>(.*?)</div>`)
+
+	codechefRatingPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)Current Rating\s*:?\s*([\d,]+)`),
+		regexp.MustCompile(`(?i)\bRating\s*:?\s*([\d,]+)\s*\(?\s*Div`),
+	}
+	codechefHighestRatingPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)Highest Rating\s*:?\s*([\d,]+)`),
+		regexp.MustCompile(`(?i)Max(?:imum)? Rating\s*:?\s*([\d,]+)`),
+	}
+	codechefGlobalRankPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)Global Rank\s*:\s*#?\s*([\d,]+)`),
+		regexp.MustCompile(`(?i)([\d,]+)\s*Global Rank`),
+	}
+	codechefCountryRankPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)Country Rank\s*:\s*#?\s*([\d,]+)`),
+		regexp.MustCompile(`(?i)([\d,]+)\s*Country Rank`),
+	}
+	codechefProblemsSolvedPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)Total Problems Solved\s*:?\s*([\d,]+)`),
+		regexp.MustCompile(`(?i)Problems Solved\s*:?\s*\(?\s*([\d,]+)`),
+		regexp.MustCompile(`(?i)Fully Solved\s*:?\s*\(?\s*([\d,]+)`),
+	}
+	codechefStarsPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(\d)\s*(?:★|☆)`),
+		regexp.MustCompile(`(?i)(\d)\s*stars?\b`),
+	}
+)
+
+// codechefStarThresholds holds the minimum rating for 2★ through 7★.
+var codechefStarThresholds = []int{1400, 1600, 1800, 2000, 2200, 2500}
+
+var codechefStarColors = []string{
+	"rgb(102, 102, 102)",
+	"rgb(30, 125, 34)",
+	"rgb(51, 102, 204)",
+	"rgb(104, 66, 115)",
+	"rgb(255, 191, 0)",
+	"rgb(255, 127, 0)",
+	"rgb(208, 1, 27)",
+}
+
+func FetchCodeChefStats(ctx context.Context, username string) (*models.CodeChefStats, models.CacheInfo, error) {
+	return cachedFetch(ctx, "codechef:"+username, CacheTTLs["codechef"], func(ctx context.Context) (*models.CodeChefStats, error) {
+		return fetchCodeChefStats(ctx, username)
+	})
+}
+
+func fetchCodeChefStats(ctx context.Context, username string) (*models.CodeChefStats, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", upstreams.CodeChef+"/users/"+url.PathEscape(username), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, requestError("CodeChef", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("CodeChef", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	stats, err := parseCodeChefProfile(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func parseCodeChefProfile(page string) (*models.CodeChefStats, error) {
+	page = codechefScriptPattern.ReplaceAllString(page, " ")
+	text := html.UnescapeString(codechefTagPattern.ReplaceAllString(page, " "))
+	text = codechefSpacePattern.ReplaceAllString(text, " ")
+	page = html.UnescapeString(page)
+
+	stats := &models.CodeChefStats{
+		Rating:         firstInt(page, codechefRatingClassPattern),
+		HighestRating:  firstInt(text, codechefHighestRatingPatterns...),
+		GlobalRank:     firstInt(text, codechefGlobalRankPatterns...),
+		CountryRank:    firstInt(text, codechefCountryRankPatterns...),
+		ProblemsSolved: firstInt(text, codechefProblemsSolvedPatterns...),
+	}
+	if stats.Rating == 0 {
+		stats.Rating = firstInt(text, codechefRatingPatterns...)
+	}
+
+	if stats.Rating == 0 && stats.ProblemsSolved == 0 && stats.GlobalRank == 0 {
+		return nil, errCodeChefProfileNotFound
+	}
+
+	if block := codechefStarBlockPattern.FindStringSubmatch(page); block != nil {
+		stats.Stars = strings.Count(block[1], "★")
+	}
+	if stats.Stars == 0 {
+		stats.Stars = firstInt(text, codechefStarsPatterns...)
+	}
+	if stats.Stars == 0 {
+		stats.Stars = codechefStarsFor(stats.Rating)
+	}
+
+	if stats.HighestRating < stats.Rating {
+		stats.HighestRating = stats.Rating
+	}
+
+	return stats, nil
+}
+
+// firstInt returns the first capture of the first pattern that matches,
+// ignoring thousands separators.
+func firstInt(text string, patterns ...*regexp.Regexp) int {
+	for _, pattern := range patterns {
+		match := pattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		value, err := strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
+		if err == nil {
+			return value
+		}
+	}
+	return 0
+}
+
+func codechefStarsFor(rating int) int {
+	stars := 1
+	for _, threshold := range codechefStarThresholds {
+		if rating >= threshold {
+			stars++
+		}
+	}
+	return stars
+}
+
+func GenerateCodeChefStatsSVG(stats *models.CodeChefStats, username, color, background string) string {
+	themeColor, bgColor, barBgColor := resolveTheme(color, background)
+
+	stars := stats.Stars
+	if stars < 1 {
+		stars = 1
+	}
+	if stars > len(codechefStarColors) {
+		stars = len(codechefStarColors)
+	}
+	starColor := codechefStarColors[stars-1]
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="300" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .stat {
+                font: 500 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .stat-title {
+                font: 400 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.8;
+            }
+            .rank {
+                font: 700 24px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                opacity: 0.9;
+            }
+            .progress-bar-bg {
+                fill: %s;
+                opacity: 0.2;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="300"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(25, 35)">
+            <text x="0" y="0" class="title">@%s's CodeChef Stats</text>
+
+            <g transform="translate(0, 55)">
+                <text class="stat-title">Rating</text>
+                <text x="0" y="25" class="rank" style="fill: %s">%d</text>
+                <text x="430" y="25" class="rank" text-anchor="end" style="fill: %s">%s</text>
+            </g>
+
+            <g transform="translate(0, 120)">
+                %s
+            </g>
+
+            <g transform="translate(0, 175)">
+                <text class="stat-title">Global Rank</text>
+                <text x="430" y="0" class="stat" text-anchor="end">#%d</text>
+
+                <text y="30" class="stat-title">Country Rank</text>
+                <text x="430" y="30" class="stat" text-anchor="end">#%d</text>
+
+                <text y="60" class="stat-title">Problems Solved</text>
+                <text x="430" y="60" class="stat" text-anchor="end">%d</text>
+            </g>
+        </g>
+    </svg>`
+
+	highestBar := progressBar(0, "Highest Rating", strconv.Itoa(stats.HighestRating), starColor,
+		float64(stats.Rating)/float64(stats.HighestRating))
+
+	return fmt.Sprintf(svgTemplate,
+		themeColor,
+		themeColor,
+		themeColor,
+		barBgColor,
+		bgColor,
+		themeColor,
+		html.EscapeString(username),
+		starColor,
+		stats.Rating,
+		starColor,
+		strings.Repeat("★", stars),
+		highestBar,
+		stats.GlobalRank,
+		stats.CountryRank,
+		stats.ProblemsSolved)
+}
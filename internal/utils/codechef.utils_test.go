@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"errors"
+	"my-realm/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readCodeChefFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", "codechef", name))
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	return string(body)
+}
+
+func TestParseCodeChefProfile(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    models.CodeChefStats
+	}{
+		{
+			fixture: "profile.html",
+			want: models.CodeChefStats{
+				Rating:         1834,
+				Stars:          4,
+				HighestRating:  1902,
+				GlobalRank:     5123,
+				CountryRank:    4321,
+				ProblemsSolved: 245,
+			},
+		},
+		{
+			fixture: "profile_drifted.html",
+			want: models.CodeChefStats{
+				Rating:         2105,
+				Stars:          5,
+				HighestRating:  2210,
+				GlobalRank:     1203,
+				CountryRank:    512,
+				ProblemsSolved: 312,
+			},
+		},
+		{
+			fixture: "profile_unrated.html",
+			want: models.CodeChefStats{
+				Rating:         1420,
+				Stars:          2,
+				HighestRating:  1420,
+				ProblemsSolved: 17,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			stats, err := parseCodeChefProfile(readCodeChefFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("parseCodeChefProfile() error = %v", err)
+			}
+			if *stats != tt.want {
+				t.Errorf("parseCodeChefProfile() = %+v, want %+v", *stats, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCodeChefProfileNotFound(t *testing.T) {
+	_, err := parseCodeChefProfile(readCodeChefFixture(t, "not_found.html"))
+	if !errors.Is(err, errCodeChefProfileNotFound) {
+		t.Fatalf("parseCodeChefProfile() error = %v, want %v", err, errCodeChefProfileNotFound)
+	}
+}
+
+func TestGenerateCodeChefStatsSVG(t *testing.T) {
+	stats, err := parseCodeChefProfile(readCodeChefFixture(t, "profile.html"))
+	if err != nil {
+		t.Fatalf("parseCodeChefProfile() error = %v", err)
+	}
+
+	svg := GenerateCodeChefStatsSVG(stats, "chefalice", "blue", "white")
+	for _, want := range []string{"@chefalice's CodeChef Stats", ">1834<", "★★★★<", "#5123", ColorSchemes["blue"]} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The kinds of upstream failure, which the controllers report as 404, 429,
+// 504 and 503 respectively. Match them with errors.Is.
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrUpstreamTimeout     = errors.New("upstream timed out")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+)
+
+// UpstreamError is a failed call to an upstream, classified by Kind.
+type UpstreamError struct {
+	// Upstream is the display name of the upstream, e.g. "GitHub".
+	Upstream string
+	Kind     error
+	// RetryAfter is how long the upstream asked us to wait, if it did.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.Upstream, e.Kind, e.Err)
+}
+
+func (e *UpstreamError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Message describes the failure for people reading an error card.
+func (e *UpstreamError) Message() string {
+	switch e.Kind {
+	case ErrNotFound:
+		return fmt.Sprintf("Not found on %s", e.Upstream)
+	case ErrRateLimited:
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("%s is rate limiting requests, try again in %s", e.Upstream, formatWait(e.RetryAfter))
+		}
+		return fmt.Sprintf("%s is rate limiting requests, try again later", e.Upstream)
+	case ErrUpstreamTimeout:
+		return fmt.Sprintf("%s took too long to respond", e.Upstream)
+	default:
+		return fmt.Sprintf("%s is unavailable right now", e.Upstream)
+	}
+}
+
+// MissingSettingError is a feature the server was started without the
+// setting for, such as an API key. It is reported as 503 rather than
+// retried, since the setting won't appear before a restart.
+type MissingSettingError struct {
+	Setting string
+	// Feature names what needs the setting, e.g. "GitHub stats".
+	Feature string
+}
+
+func (e *MissingSettingError) Error() string {
+	return e.Setting + " is not set"
+}
+
+// Message describes the failure for people reading an error card.
+func (e *MissingSettingError) Message() string {
+	return fmt.Sprintf("%s need %s, which this server is not configured with", e.Feature, e.Setting)
+}
+
+func notFoundError(upstream string, err error) error {
+	return &UpstreamError{Upstream: upstream, Kind: ErrNotFound, Err: err}
+}
+
+// requestError classifies a request to upstream that got no response.
+func requestError(upstream string, err error) error {
+	// The transport already classified calls it refused to make.
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return &UpstreamError{Upstream: upstream, Kind: upstreamErr.Kind, RetryAfter: upstreamErr.RetryAfter, Err: upstreamErr.Err}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &UpstreamError{Upstream: upstream, Kind: ErrUpstreamTimeout, Err: err}
+	}
+	return &UpstreamError{Upstream: upstream, Kind: ErrUpstreamUnavailable, Err: err}
+}
+
+// statusError classifies an unexpected response from upstream by its status.
+func statusError(upstream string, resp *http.Response) error {
+	err := fmt.Errorf("returned status %d", resp.StatusCode)
+	retryAfter := retryAfter(resp.Header)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return notFoundError(upstream, err)
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && (retryAfter > 0 || resp.Header.Get("X-RateLimit-Remaining") == "0"):
+		if retryAfter == 0 {
+			retryAfter = rateLimitReset(resp.Header)
+		}
+		return &UpstreamError{Upstream: upstream, Kind: ErrRateLimited, RetryAfter: retryAfter, Err: err}
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		return &UpstreamError{Upstream: upstream, Kind: ErrUpstreamTimeout, Err: err}
+	case resp.StatusCode >= 500:
+		return &UpstreamError{Upstream: upstream, Kind: ErrUpstreamUnavailable, RetryAfter: retryAfter, Err: err}
+	default:
+		return fmt.Errorf("%s %w", upstream, err)
+	}
+}
+
+// retryAfter reads a Retry-After header given in seconds or as a date.
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// rateLimitReset reads how long until an X-RateLimit-Reset time.
+func rateLimitReset(header http.Header) time.Duration {
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return max(time.Until(time.Unix(reset, 0)), 0)
+}
+
+func formatWait(wait time.Duration) string {
+	if wait < time.Minute {
+		return "a minute"
+	}
+	minutes := int(wait.Round(time.Minute).Minutes())
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+// graphQLError classifies the first error of a GraphQL response, going by its
+// type where the API reports one and its message otherwise.
+func graphQLError(upstream, errorType, message string) error {
+	err := fmt.Errorf("API error: %s", message)
+	switch {
+	case errorType == "NOT_FOUND", strings.Contains(strings.ToLower(message), "does not exist"):
+		return notFoundError(upstream, err)
+	case errorType == "RATE_LIMITED":
+		return &UpstreamError{Upstream: upstream, Kind: ErrRateLimited, Err: err}
+	default:
+		return fmt.Errorf("%s %w", upstream, err)
+	}
+}
@@ -0,0 +1,429 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"my-realm/internal/models"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FeedMaxPosts is the most posts kept per feed and shown on a card.
+const FeedMaxPosts = 10
+
+// feedMaxBytes caps how much of a feed document is read.
+const feedMaxBytes = 5 << 20
+
+// feedWordsPerMinute is used to estimate reading time from post content.
+const feedWordsPerMinute = 200
+
+var ErrFeedHostNotAllowed = errors.New("feed host is not allowed")
+
+// DefaultFeedHosts are the blogging platforms whose feeds can always be
+// fetched. A host matches itself and any of its subdomains.
+var DefaultFeedHosts = []string{
+	"dev.to",
+	"hashnode.com",
+	"hashnode.dev",
+	"medium.com",
+	"substack.com",
+	"blogspot.com",
+	"wordpress.com",
+	"github.io",
+}
+
+var (
+	feedTagPattern  = regexp.MustCompile(`<[^>]+>`)
+	feedDateLayouts = []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC3339,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"2006-01-02T15:04:05Z0700",
+		"2006-01-02",
+	}
+)
+
+// FetchFeed fetches and parses an RSS 2.0 or Atom feed. The feed URL, and any
+// redirect it follows, must point at one of allowedHosts, or of
+// DefaultFeedHosts when allowedHosts is empty.
+func FetchFeed(ctx context.Context, feedURL string, allowedHosts []string) (*models.Feed, models.CacheInfo, error) {
+	hosts := allowedHosts
+	if len(hosts) == 0 {
+		hosts = DefaultFeedHosts
+	}
+	if err := checkFeedURL(feedURL, hosts); err != nil {
+		return nil, models.CacheInfo{}, err
+	}
+
+	return cachedFetch(ctx, "feed:url:"+feedURL, CacheTTLs["feed"], func(ctx context.Context) (*models.Feed, error) {
+		client := *httpClient
+		client.CheckRedirect = func(req *http.Request, _ []*http.Request) error {
+			return checkFeedURL(req.URL.String(), hosts)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", feedURL, http.NoBody)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, requestError(req.URL.Host, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, statusError(req.URL.Host, resp)
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, feedMaxBytes))
+		if err != nil {
+			return nil, fmt.Errorf("error reading response: %w", err)
+		}
+
+		return ParseFeed(body)
+	})
+}
+
+func FetchDevToFeed(ctx context.Context, username string) (*models.Feed, models.CacheInfo, error) {
+	return cachedFetch(ctx, "feed:devto:"+username, CacheTTLs["feed"], func(ctx context.Context) (*models.Feed, error) {
+		resp, err := httpGet(ctx, fmt.Sprintf("%s/articles?username=%s&per_page=%d",
+			upstreams.DevTo, url.QueryEscape(username), FeedMaxPosts))
+		if err != nil {
+			return nil, requestError("DEV", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, statusError("DEV", resp)
+		}
+
+		var articles []models.DevToArticle
+		if err := json.NewDecoder(resp.Body).Decode(&articles); err != nil {
+			return nil, fmt.Errorf("error decoding response: %w", err)
+		}
+
+		feed := &models.Feed{
+			Title: fmt.Sprintf("@%s on DEV", username),
+			Link:  "https://dev.to/" + url.PathEscape(username),
+			Posts: []models.FeedPost{},
+		}
+		for _, article := range articles {
+			reactions := article.PublicReactionsCount
+			feed.Posts = append(feed.Posts, models.FeedPost{
+				Title:              article.Title,
+				URL:                article.URL,
+				PublishedAt:        article.PublishedAt,
+				ReadingTimeMinutes: article.ReadingTimeMinutes,
+				Reactions:          &reactions,
+			})
+		}
+
+		return feed, nil
+	})
+}
+
+func FetchHashnodeFeed(ctx context.Context, username string) (*models.Feed, models.CacheInfo, error) {
+	return cachedFetch(ctx, "feed:hashnode:"+username, CacheTTLs["feed"], func(ctx context.Context) (*models.Feed, error) {
+		query := `
+    query UserPosts($username: String!, $pageSize: Int!) {
+        user(username: $username) {
+            name
+            posts(page: 1, pageSize: $pageSize) {
+                nodes {
+                    title
+                    url
+                    publishedAt
+                    readTimeInMinutes
+                    reactionCount
+                }
+            }
+        }
+    }`
+
+		requestBody, err := json.Marshal(map[string]any{
+			"query": query,
+			"variables": map[string]any{
+				"username": username,
+				"pageSize": FeedMaxPosts,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", upstreams.Hashnode, bytes.NewBuffer(requestBody))
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		markRead(req)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, requestError("Hashnode", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, statusError("Hashnode", resp)
+		}
+
+		var result models.HashnodeResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("error decoding response: %w", err)
+		}
+
+		if len(result.Errors) > 0 {
+			return nil, graphQLError("Hashnode", "", result.Errors[0].Message)
+		}
+		if result.Data.User == nil {
+			return nil, notFoundError("Hashnode", fmt.Errorf("user %s not found", username))
+		}
+
+		feed := &models.Feed{
+			Title: fmt.Sprintf("@%s on Hashnode", username),
+			Link:  "https://hashnode.com/@" + url.PathEscape(username),
+			Posts: []models.FeedPost{},
+		}
+		for _, node := range result.Data.User.Posts.Nodes {
+			reactions := node.ReactionCount
+			feed.Posts = append(feed.Posts, models.FeedPost{
+				Title:              node.Title,
+				URL:                node.URL,
+				PublishedAt:        node.PublishedAt,
+				ReadingTimeMinutes: node.ReadTimeInMinutes,
+				Reactions:          &reactions,
+			})
+		}
+
+		return feed, nil
+	})
+}
+
+func checkFeedURL(rawURL string, allowedHosts []string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid feed url: %w", err)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrFeedHostNotAllowed, parsed.Scheme)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed != "" && (host == allowed || strings.HasSuffix(host, "."+allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrFeedHostNotAllowed, host)
+}
+
+// ParseFeed reads an RSS 2.0 or Atom document, newest posts first.
+func ParseFeed(body []byte) (*models.Feed, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("error decoding feed: %w", err)
+	}
+
+	var feed *models.Feed
+	switch root.XMLName.Local {
+	case "rss":
+		var doc models.RSSDocument
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("error decoding rss: %w", err)
+		}
+		feed = &models.Feed{Title: strings.TrimSpace(doc.Channel.Title), Link: strings.TrimSpace(doc.Channel.Link)}
+		for _, item := range doc.Channel.Items {
+			content := item.Content
+			if content == "" {
+				content = item.Description
+			}
+			feed.Posts = append(feed.Posts, models.FeedPost{
+				Title:              strings.TrimSpace(item.Title),
+				URL:                strings.TrimSpace(item.Link),
+				PublishedAt:        parseFeedDate(item.PubDate),
+				ReadingTimeMinutes: estimateReadingTime(content),
+			})
+		}
+	case "feed":
+		var doc models.AtomDocument
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("error decoding atom: %w", err)
+		}
+		feed = &models.Feed{Title: strings.TrimSpace(doc.Title)}
+		for _, link := range doc.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				feed.Link = link.Href
+				break
+			}
+		}
+		for _, entry := range doc.Entries {
+			post := models.FeedPost{Title: strings.TrimSpace(entry.Title)}
+			for _, link := range entry.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					post.URL = link.Href
+					break
+				}
+			}
+			published := entry.Published
+			if published == "" {
+				published = entry.Updated
+			}
+			post.PublishedAt = parseFeedDate(published)
+			content := entry.Content
+			if content == "" {
+				content = entry.Summary
+			}
+			post.ReadingTimeMinutes = estimateReadingTime(content)
+			feed.Posts = append(feed.Posts, post)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported feed format %q", root.XMLName.Local)
+	}
+
+	sort.SliceStable(feed.Posts, func(i, j int) bool {
+		return feed.Posts[i].PublishedAt.After(feed.Posts[j].PublishedAt)
+	})
+	if len(feed.Posts) > FeedMaxPosts {
+		feed.Posts = feed.Posts[:FeedMaxPosts]
+	}
+	if feed.Posts == nil {
+		feed.Posts = []models.FeedPost{}
+	}
+
+	return feed, nil
+}
+
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// estimateReadingTime returns minutes at feedWordsPerMinute, or 0 when the
+// feed carries no content to estimate from.
+func estimateReadingTime(content string) int {
+	text := html.UnescapeString(feedTagPattern.ReplaceAllString(html.UnescapeString(content), " "))
+	words := len(strings.Fields(text))
+	if words == 0 {
+		return 0
+	}
+	return (words + feedWordsPerMinute - 1) / feedWordsPerMinute
+}
+
+func truncateText(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+func GenerateFeedSVG(feed *models.Feed, limit int, color, background string) string {
+	themeColor, bgColor, _ := resolveTheme(color, background)
+
+	posts := feed.Posts
+	if limit > 0 && len(posts) > limit {
+		posts = posts[:limit]
+	}
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="%d" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .post-title {
+                font: 500 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .post-meta {
+                font: 400 12px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.7;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="%d"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(30, 35)">
+            <text x="0" y="0" class="title">%s</text>
+            <g transform="translate(0, 35)">
+                %s
+            </g>
+        </g>
+    </svg>`
+
+	var rows strings.Builder
+	for i, post := range posts {
+		var meta []string
+		if !post.PublishedAt.IsZero() {
+			meta = append(meta, post.PublishedAt.Format("Jan 2, 2006"))
+		}
+		if post.ReadingTimeMinutes > 0 {
+			meta = append(meta, fmt.Sprintf("%d min read", post.ReadingTimeMinutes))
+		}
+		if post.Reactions != nil {
+			meta = append(meta, fmt.Sprintf("♥ %d", *post.Reactions))
+		}
+
+		rows.WriteString(fmt.Sprintf(`
+                <g transform="translate(0, %d)">
+                    <text x="0" y="0" class="post-title">%s</text>
+                    <text x="0" y="18" class="post-meta">%s</text>
+                </g>`, i*45, html.EscapeString(truncateText(post.Title, 55)), strings.Join(meta, " · ")))
+	}
+
+	title := feed.Title
+	if title == "" {
+		title = "Latest Posts"
+	}
+
+	height := 85 + len(posts)*45
+
+	return fmt.Sprintf(svgTemplate,
+		height,
+		themeColor,
+		themeColor,
+		themeColor,
+		height,
+		bgColor,
+		themeColor,
+		html.EscapeString(truncateText(title, 45)),
+		rows.String())
+}
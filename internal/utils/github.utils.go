@@ -2,38 +2,41 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"html"
+	"my-realm/internal/config"
 	"my-realm/internal/models"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
-	"sync"
-	"time"
 )
 
 const neutral = "#171717"
 const white = "white"
 const gray = "#E5E5E5"
 
-var (
-	githubCache = make(map[string]models.GitHubCache)
-	githubMutex sync.RWMutex
-	githubTTL   = 10 * time.Minute
-)
-
-func FetchGitHubStats(username, token string) (models.ProfileStats, error) {
-	githubMutex.RLock()
-	if cached, exists := githubCache[username]; exists {
-		if time.Since(cached.Timestamp) < githubTTL {
-			githubMutex.RUnlock()
-			return cached.Stats, nil
-		}
+// FetchGitHubStats returns the contribution stats of username on instance,
+// which needs a token since GitHub's GraphQL API refuses anonymous calls.
+func FetchGitHubStats(ctx context.Context, instance models.GitHubInstance, username string) (models.ProfileStats, models.CacheInfo, error) {
+	if len(instance.Tokens) == 0 {
+		return models.ProfileStats{}, models.CacheInfo{}, githubTokenMissing(instance)
+	}
+	if githubGraphQLURL(instance) == "" {
+		return models.ProfileStats{}, models.CacheInfo{}, githubSettingMissing(instance, "GRAPHQL_URL")
 	}
-	githubMutex.RUnlock()
 
-	query := fmt.Sprintf(`{
-        user(login: "%s") {
+	return cachedFetch(ctx, "github:"+instance.Name+":"+username, CacheTTLs["github"], func(ctx context.Context) (models.ProfileStats, error) {
+		return fetchGitHubStats(ctx, instance, username)
+	})
+}
+
+func fetchGitHubStats(ctx context.Context, instance models.GitHubInstance, username string) (models.ProfileStats, error) {
+	query := `
+    query UserStats($login: String!) {
+        user(login: $login) {
             contributionsCollection {
                 totalCommitContributions
                 totalPullRequestContributions
@@ -49,36 +52,63 @@ func FetchGitHubStats(username, token string) (models.ProfileStats, error) {
                     }
                 }
             }
+            followers {
+                totalCount
+            }
+            repositories(ownerAffiliations: OWNER, isFork: false, first: 100, orderBy: {field: STARGAZERS, direction: DESC}) {
+                nodes {
+                    stargazerCount
+                }
+            }
         }
-    }`, username)
+        rateLimit {
+            remaining
+            resetAt
+            cost
+        }
+    }`
 
-	requestBody, err := json.Marshal(map[string]string{
+	requestBody, err := json.Marshal(map[string]any{
 		"query": query,
+		"variables": map[string]any{
+			"login": username,
+		},
 	})
 	if err != nil {
 		return models.ProfileStats{}, err
 	}
 
-	req, err := http.NewRequest("POST", "https://api.github.com/graphql", bytes.NewBuffer(requestBody))
+	resp, token, err := githubDo(ctx, instance.Tokens, githubGraphQL, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", githubGraphQLURL(instance), bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		markRead(req)
+		return req, nil
+	})
 	if err != nil {
 		return models.ProfileStats{}, err
 	}
+	defer resp.Body.Close()
 
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return models.ProfileStats{}, err
+	if resp.StatusCode != http.StatusOK {
+		return models.ProfileStats{}, statusError("GitHub", resp)
 	}
-	defer resp.Body.Close()
 
 	var graphQLResp models.GraphQLResponse
 	if err := json.NewDecoder(resp.Body).Decode(&graphQLResp); err != nil {
 		return models.ProfileStats{}, err
 	}
 
+	if rateLimit := graphQLResp.Data.RateLimit; token != "" && rateLimit != nil {
+		recordQuota(token, githubGraphQL, rateLimit.Remaining, rateLimit.ResetAt)
+	}
+
+	if len(graphQLResp.Errors) > 0 {
+		return models.ProfileStats{}, graphQLError("GitHub", graphQLResp.Errors[0].Type, graphQLResp.Errors[0].Message)
+	}
+
 	var contributionsByDay []models.DayContribution
 	for _, week := range graphQLResp.Data.User.ContributionsCollection.ContributionCalendar.Weeks {
 		for _, day := range week.ContributionDays {
@@ -95,19 +125,98 @@ func FetchGitHubStats(username, token string) (models.ProfileStats, error) {
 		TotalCommits:       graphQLResp.Data.User.ContributionsCollection.TotalCommitContributions,
 		TotalPRs:           graphQLResp.Data.User.ContributionsCollection.TotalPullRequestContributions,
 		TotalIssues:        graphQLResp.Data.User.ContributionsCollection.TotalIssueContributions,
+		Followers:          graphQLResp.Data.User.Followers.TotalCount,
 		ContributionsByDay: contributionsByDay,
 	}
 
-	githubMutex.Lock()
-	githubCache[username] = models.GitHubCache{
-		Stats:     stats,
-		Timestamp: time.Now(),
+	// Stars are summed over the 100 most starred repositories, which covers
+	// nearly all of them for almost every user.
+	for _, repo := range graphQLResp.Data.User.Repositories.Nodes {
+		stats.Stars += repo.StargazerCount
 	}
-	githubMutex.Unlock()
 
 	return stats, nil
 }
 
+// FetchUserRepos returns the public repositories of username on instance.
+// Expired entries are revalidated with the ETag and Last-Modified GitHub sent,
+// so unchanged repositories cost a 304 that does not count against the rate
+// limit instead of a full response.
+func FetchUserRepos(ctx context.Context, instance models.GitHubInstance, username string) ([]models.GitHubRepository, models.CacheInfo, error) {
+	return cachedConditionalFetch(ctx, "github:repos:"+instance.Name+":"+username, CacheTTLs["github"], func(ctx context.Context, cached models.CacheEntry[[]models.GitHubRepository]) (models.CacheEntry[[]models.GitHubRepository], error) {
+		return fetchUserRepos(ctx, instance, username, cached)
+	})
+}
+
+func fetchUserRepos(ctx context.Context, instance models.GitHubInstance, username string, cached models.CacheEntry[[]models.GitHubRepository]) (models.CacheEntry[[]models.GitHubRepository], error) {
+	var entry models.CacheEntry[[]models.GitHubRepository]
+
+	resp, _, err := githubDo(ctx, instance.Tokens, githubCore, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/users/%s/repos", githubAPIURL(instance), url.PathEscape(username)), http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return entry, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && !cached.Timestamp.IsZero() {
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return entry, statusError("GitHub", resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&entry.Value); err != nil {
+		return entry, err
+	}
+	entry.ETag = resp.Header.Get("ETag")
+	entry.LastModified = resp.Header.Get("Last-Modified")
+
+	return entry, nil
+}
+
+// githubAPIURL is the REST base URL of instance. Only the default host falls
+// back to github.com; a named host always has its own URL, see
+// config.Env.GitHubInstance, so its token never leaves it.
+func githubAPIURL(instance models.GitHubInstance) string {
+	if instance.APIURL != "" || instance.Name != "" {
+		return strings.TrimSuffix(instance.APIURL, "/")
+	}
+	return upstreams.GitHubAPI
+}
+
+// githubGraphQLURL is the GraphQL URL of instance, or empty when a host
+// with its own REST URL has none, rather than github.com's.
+func githubGraphQLURL(instance models.GitHubInstance) string {
+	if instance.GraphQLURL != "" || instance.APIURL != "" || instance.Name != "" {
+		return instance.GraphQLURL
+	}
+	return upstreams.GitHubGraphQL
+}
+
+// CountLanguages tallies the primary language of each repository, skipping
+// repositories GitHub could not classify.
+func CountLanguages(repos []models.GitHubRepository) (languageCount map[string]int, totalRepos int) {
+	languageCount = make(map[string]int)
+	for _, repo := range repos {
+		if repo.Language != "" {
+			languageCount[repo.Language]++
+			totalRepos++
+		}
+	}
+	return languageCount, totalRepos
+}
+
 func GenerateLanguagesSVG(languageCount map[string]int, totalRepos int, username, color, background string) string {
 	themeColor := ColorSchemes[color]
 	if themeColor == "" {
@@ -196,30 +305,7 @@ func GenerateLanguagesSVG(languageCount map[string]int, totalRepos int, username
 	height := baseHeight + (len(languages) * 40)
 
 	for i, lang := range languages {
-		languageBars.WriteString(fmt.Sprintf(`
-            <g transform="translate(0, %d)">
-                <text x="0" y="0" class="lang-text">%s</text>
-                <text x="440" y="0" class="percentage-text" text-anchor="end">%.1f%%</text>
-                <g transform="translate(0, 10)">
-                    <rect 
-                        x="0" 
-                        y="0" 
-                        width="440" 
-                        height="8" 
-                        rx="4" 
-                        class="bar-bg"
-                    />
-                    <rect 
-                        x="0" 
-                        y="0" 
-                        width="%.1f" 
-                        height="8" 
-                        rx="4" 
-                        class="bar"
-                    />
-                </g>
-            </g>
-        `, i*40, lang.Name, lang.Percentage, 440*(lang.Percentage/100)))
+		languageBars.WriteString(horizontalBar(i*40, lang.Name, fmt.Sprintf("%.1f%%", lang.Percentage), lang.Percentage))
 	}
 
 	return fmt.Sprintf(svgTemplate,
@@ -232,7 +318,7 @@ func GenerateLanguagesSVG(languageCount map[string]int, totalRepos int, username
 		height,
 		bgColor,
 		themeColor,
-		username,
+		html.EscapeString(username),
 		languageBars.String())
 }
 
@@ -407,7 +493,7 @@ func GenerateStatsSVG(stats models.ProfileStats, username string, color string,
 		themeColor,
 		bgColor,
 		themeColor,
-		username,
+		html.EscapeString(username),
 		stats.TotalContributions,
 		stats.TotalCommits,
 		stats.TotalPRs,
@@ -419,3 +505,18 @@ func getDayName(weekday int) string {
 	days := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
 	return days[weekday]
 }
+
+// githubTokenMissing names the setting instance's token is read from.
+func githubTokenMissing(instance models.GitHubInstance) error {
+	return githubSettingMissing(instance, "TOKEN")
+}
+
+// githubSettingMissing names the GITHUB_<NAME>_ setting of instance, or the
+// GITHUB_ one of the default host, that it lacks.
+func githubSettingMissing(instance models.GitHubInstance, setting string) error {
+	prefix := "GITHUB_"
+	if instance.Name != "" {
+		prefix += config.EnvName(instance.Name) + "_"
+	}
+	return &MissingSettingError{Setting: prefix + setting, Feature: "GitHub stats"}
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"my-realm/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchUserReposRevalidatesWithETag(t *testing.T) {
+	useTestCache(t)
+
+	const etag = `"repos-v1"`
+	var full, notModified atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Sun, 02 Jun 2024 10:00:00 GMT")
+		_, _ = io.WriteString(w, `[{"language": "Go"}, {"language": "Rust"}]`)
+	}))
+	t.Cleanup(server.Close)
+
+	previousTTL := CacheTTLs["github"]
+	CacheTTLs["github"] = 50 * time.Millisecond
+	t.Cleanup(func() { CacheTTLs["github"] = previousTTL })
+
+	instance := models.GitHubInstance{APIURL: server.URL}
+	repos, first, err := FetchUserRepos(context.Background(), instance, "octocat")
+	if err != nil || len(repos) != 2 {
+		t.Fatalf("first fetch = %v, %v; want 2 repositories", repos, err)
+	}
+
+	time.Sleep(2 * CacheTTLs["github"])
+
+	// The expired entry is served stale while it is revalidated.
+	deadline := time.Now().Add(time.Second)
+	for {
+		repos, info, err := FetchUserRepos(context.Background(), instance, "octocat")
+		if err != nil || len(repos) != 2 {
+			t.Fatalf("revalidated fetch = %v, %v; want 2 repositories", repos, err)
+		}
+		if !info.Stale && info.FetchedAt.After(first.FetchedAt) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("entry was not revalidated, last info %+v", info)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if full.Load() != 1 || notModified.Load() != 1 {
+		t.Errorf("upstream served %d full and %d not modified responses, want 1 and 1", full.Load(), notModified.Load())
+	}
+}
+
+func TestFetchGitHubStatsKeepsEnterpriseTokensOffGitHub(t *testing.T) {
+	useTestCache(t)
+
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	t.Cleanup(server.Close)
+
+	previous := upstreams
+	u := DefaultUpstreams()
+	u.GitHubGraphQL = server.URL
+	SetUpstreams(u)
+	t.Cleanup(func() { SetUpstreams(previous) })
+
+	instance := models.GitHubInstance{APIURL: "https://ghe.example.com/rest", Tokens: []string{"ghe-token"}}
+	_, _, err := FetchGitHubStats(context.Background(), instance, "octocat")
+	var missingErr *MissingSettingError
+	if !errors.As(err, &missingErr) || missingErr.Setting != "GITHUB_GRAPHQL_URL" {
+		t.Errorf("err = %v, want GITHUB_GRAPHQL_URL reported missing", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("github.com was called %d times with the Enterprise token", calls.Load())
+	}
+}
+
+func TestCardsEscapeUserStrings(t *testing.T) {
+	const name = `<a href="x">&</a>`
+	cards := map[string]string{
+		"stats":     GenerateStatsSVG(models.ProfileStats{}, name, "red", "black"),
+		"languages": GenerateLanguagesSVG(map[string]int{name: 1}, 1, name, "red", "black"),
+		"practice": GeneratePracticeSVG([]*models.PracticeStats{{
+			Platform:  "Codewars",
+			Username:  name,
+			Rank:      name,
+			Languages: []models.PracticeLanguage{{Name: name, Completed: 1}},
+		}}, "red", "black"),
+	}
+
+	for card, svg := range cards {
+		if strings.Contains(svg, name) {
+			t.Errorf("%s card contains the unescaped string:\n%s", card, svg)
+		}
+		if !strings.Contains(svg, "&lt;a href=&#34;x&#34;&gt;&amp;&lt;/a&gt;") {
+			t.Errorf("%s card does not contain the escaped string:\n%s", card, svg)
+		}
+	}
+}
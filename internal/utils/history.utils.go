@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"my-realm/internal/config"
+	"my-realm/internal/history"
+	"my-realm/internal/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+var historyStore history.Store
+
+// SetHistory sets the store snapshots are kept in. Without one, nothing is
+// recorded and LoadHistory fails with ErrHistoryDisabled.
+func SetHistory(s history.Store) {
+	historyStore = s
+}
+
+var (
+	ErrHistoryDisabled = errors.New("history is not enabled")
+	ErrUnknownMetric   = errors.New("unknown history metric")
+)
+
+// HistoryMetrics lists the metrics snapshotted for each provider.
+var HistoryMetrics = map[string][]string{
+	"github":   {"contributions", "commits", "pull_requests", "issues", "stars", "followers"},
+	"leetcode": {"total_solved", "easy_solved", "medium_solved", "hard_solved", "ranking", "acceptance_rate", "contest_rating"},
+}
+
+// TakeSnapshot fetches the current metrics of username on provider. GitHub
+// users are looked up on the default host. The snapshot is taken when the
+// metrics were fetched, which is earlier than now when they come from the
+// cache, so stale data is never recorded as today's.
+func TakeSnapshot(ctx context.Context, provider, username string) (models.Snapshot, error) {
+	// Both providers ignore case in usernames, so one user has one history.
+	username = strings.ToLower(username)
+	snapshot := models.Snapshot{
+		Provider: provider,
+		Username: username,
+	}
+
+	switch provider {
+	case "github":
+		instance, _ := config.Current().GitHubInstance("")
+		stats, info, err := FetchGitHubStats(ctx, instance, username)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.TakenAt = info.FetchedAt
+		snapshot.Metrics = map[string]float64{
+			"contributions": float64(stats.TotalContributions),
+			"commits":       float64(stats.TotalCommits),
+			"pull_requests": float64(stats.TotalPRs),
+			"issues":        float64(stats.TotalIssues),
+			"stars":         float64(stats.Stars),
+			"followers":     float64(stats.Followers),
+		}
+	case "leetcode":
+		stats, info, err := FetchLeetCodeStats(ctx, username)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.TakenAt = info.FetchedAt
+		snapshot.Metrics = map[string]float64{
+			"total_solved":    float64(stats.TotalSolved),
+			"easy_solved":     float64(stats.EasySolved),
+			"medium_solved":   float64(stats.MediumSolved),
+			"hard_solved":     float64(stats.HardSolved),
+			"ranking":         float64(stats.Ranking),
+			"acceptance_rate": stats.AcceptanceRate,
+			"contest_rating":  stats.ContestRating,
+		}
+	default:
+		return snapshot, fmt.Errorf("no history for provider %q", provider)
+	}
+	return snapshot, nil
+}
+
+// RecordSnapshots snapshots every tracked user, replacing the snapshot they
+// already have for today, and drops snapshots older than retention.
+func RecordSnapshots(ctx context.Context, tracked []models.TrackedUser, retention time.Duration) error {
+	if historyStore == nil {
+		return ErrHistoryDisabled
+	}
+
+	var errs []error
+	for _, user := range tracked {
+		snapshot, err := TakeSnapshot(ctx, user.Provider, user.Username)
+		if err == nil {
+			err = historyStore.Record(snapshot)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%s: %w", user.Provider, user.Username, err))
+		}
+	}
+
+	if retention > 0 {
+		if err := historyStore.Prune(time.Now().Add(-retention)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunHistoryRecorder records snapshots of the tracked users right away and
+// then every interval until ctx is done. Recording more than once a day keeps
+// today's snapshot up to date and covers days the server was down at the
+// usual time.
+func RunHistoryRecorder(ctx context.Context, tracked []models.TrackedUser, retention, interval time.Duration) {
+	if historyStore == nil || len(tracked) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := RecordSnapshots(ctx, tracked, retention); err != nil {
+			log.Printf("error recording history: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LoadHistory returns the daily values of metric for username on provider
+// since since.
+func LoadHistory(provider, username, metric string, since time.Time) (models.HistorySeries, error) {
+	username = strings.ToLower(username)
+	series := models.HistorySeries{Provider: provider, Username: username, Metric: metric}
+	if historyStore == nil {
+		return series, ErrHistoryDisabled
+	}
+	if !slices.Contains(HistoryMetrics[provider], metric) {
+		return series, ErrUnknownMetric
+	}
+
+	points, err := historyStore.Series(provider, username, metric, since)
+	if err != nil {
+		return series, err
+	}
+	series.Points = points
+	return series, nil
+}
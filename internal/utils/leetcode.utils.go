@@ -2,30 +2,22 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"my-realm/internal/models"
 	"net/http"
-	"sync"
-	"time"
+	"strconv"
 )
 
-var (
-	cache      = make(map[string]models.LeetCodeCache)
-	cacheMutex sync.RWMutex
-	cacheTTL   = 10 * time.Minute
-)
-
-func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
-	cacheMutex.RLock()
-	if cached, exists := cache[username]; exists {
-		if time.Since(cached.Timestamp) < cacheTTL {
-			cacheMutex.RUnlock()
-			return cached.Stats, nil
-		}
-	}
-	cacheMutex.RUnlock()
+func FetchLeetCodeStats(ctx context.Context, username string) (*models.LeetCodeStats, models.CacheInfo, error) {
+	return cachedFetch(ctx, "leetcode:"+username, CacheTTLs["leetcode"], func(ctx context.Context) (*models.LeetCodeStats, error) {
+		return fetchLeetCodeStats(ctx, username)
+	})
+}
 
+func fetchLeetCodeStats(ctx context.Context, username string) (*models.LeetCodeStats, error) {
 	query := `
     query userSessionProgress($username: String!) {
         allQuestionsCount {
@@ -49,6 +41,9 @@ func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
                 }
             }
         }
+        userContestRanking(username: $username) {
+            rating
+        }
     }`
 
 	requestBody := map[string]interface{}{
@@ -64,24 +59,25 @@ func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
 		return nil, fmt.Errorf("error marshaling request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://leetcode.com/graphql", bytes.NewBuffer(jsonValue))
+	req, err := http.NewRequestWithContext(ctx, "POST", upstreams.LeetCodeGraphQL, bytes.NewBuffer(jsonValue))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0")
+	markRead(req)
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, requestError("LeetCode", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("LeetCode", resp)
+	}
+
 	var result struct {
 		Data struct {
 			AllQuestionsCount []struct {
@@ -105,6 +101,10 @@ func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
 					} `json:"totalSubmissionNum"`
 				} `json:"submitStats"`
 			} `json:"matchedUser"`
+			// UserContestRanking is null for users who never entered a contest.
+			UserContestRanking *struct {
+				Rating float64 `json:"rating"`
+			} `json:"userContestRanking"`
 		} `json:"data"`
 		Errors []struct {
 			Message string `json:"message"`
@@ -116,7 +116,7 @@ func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
 	}
 
 	if len(result.Errors) > 0 {
-		return nil, fmt.Errorf("leetcode API error: %s", result.Errors[0].Message)
+		return nil, graphQLError("LeetCode", "", result.Errors[0].Message)
 	}
 
 	stats := &models.LeetCodeStats{}
@@ -144,18 +144,14 @@ func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
 
 	stats.TotalSolved = stats.EasySolved + stats.MediumSolved + stats.HardSolved
 	stats.Ranking = result.Data.MatchedUser.Profile.Ranking
+	if result.Data.UserContestRanking != nil {
+		stats.ContestRating = result.Data.UserContestRanking.Rating
+	}
 
 	if totalSubmissions > 0 {
 		stats.AcceptanceRate = float64(acceptedSubmissions) / float64(totalSubmissions) * 100
 	}
 
-	cacheMutex.Lock()
-	cache[username] = models.LeetCodeCache{
-		Stats:     stats,
-		Timestamp: time.Now(),
-	}
-	cacheMutex.Unlock()
-
 	return stats, nil
 }
 
@@ -170,9 +166,6 @@ func GenerateLeetCodeStatsSVG(stats *models.LeetCodeStats, username, color, back
 		bgColor = BackgroundSchemes["black"]
 	}
 
-	easyPercent := float64(stats.EasySolved) / float64(stats.TotalQuestions) * 100
-	mediumPercent := float64(stats.MediumSolved) / float64(stats.TotalQuestions) * 100
-	hardPercent := float64(stats.HardSolved) / float64(stats.TotalQuestions) * 100
 	totalPercent := float64(stats.TotalSolved) / float64(stats.TotalQuestions) * 100
 
 	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
@@ -241,26 +234,7 @@ func GenerateLeetCodeStatsSVG(stats *models.LeetCodeStats, username, color, back
             <g transform="translate(0, 175)">
                 <text class="stat-title">Problems Solved</text>
             
-                <g transform="translate(0, 30)">
-                    <text class="stat-title" style="fill: rgb(0, 184, 163)">Easy</text>
-                    <text x="430" y="0" class="stat" text-anchor="end">%d</text>
-                    <rect x="0" y="10" width="440" height="8" rx="4" class="progress-bar-bg"/>
-                    <rect x="0" y="10" width="%.1f" height="8" rx="4" style="fill: rgb(0, 184, 163); opacity: 0.8;"/>
-                </g>
-
-                <g transform="translate(0, 75)">
-                    <text class="stat-title" style="fill: rgb(255, 192, 30)">Medium</text>
-                    <text x="430" y="0" class="stat" text-anchor="end">%d</text>
-                    <rect x="0" y="10" width="440" height="8" rx="4" class="progress-bar-bg"/>
-                    <rect x="0" y="10" width="%.1f" height="8" rx="4" style="fill: rgb(255, 192, 30); opacity: 0.8;"/>
-                </g>
-
-                <g transform="translate(0, 120)">
-                    <text class="stat-title" style="fill: rgb(255, 55, 95)">Hard</text>
-                    <text x="430" y="0" class="stat" text-anchor="end">%d</text>
-                    <rect x="0" y="10" width="440" height="8" rx="4" class="progress-bar-bg"/>
-                    <rect x="0" y="10" width="%.1f" height="8" rx="4" style="fill: rgb(255, 55, 95); opacity: 0.8;"/>
-                </g>
+                %s
             </g>
 
             <g transform="translate(0, 350)">
@@ -275,6 +249,11 @@ func GenerateLeetCodeStatsSVG(stats *models.LeetCodeStats, username, color, back
 		barBgColor = "#E5E5E5"
 	}
 
+	total := float64(stats.TotalQuestions)
+	difficultyBars := progressBar(30, "Easy", strconv.Itoa(stats.EasySolved), "rgb(0, 184, 163)", float64(stats.EasySolved)/total) +
+		progressBar(75, "Medium", strconv.Itoa(stats.MediumSolved), "rgb(255, 192, 30)", float64(stats.MediumSolved)/total) +
+		progressBar(120, "Hard", strconv.Itoa(stats.HardSolved), "rgb(255, 55, 95)", float64(stats.HardSolved)/total)
+
 	return fmt.Sprintf(svgTemplate,
 		themeColor,
 		themeColor,
@@ -284,16 +263,11 @@ func GenerateLeetCodeStatsSVG(stats *models.LeetCodeStats, username, color, back
 		themeColor,
 		bgColor,
 		themeColor,
-		username,
+		html.EscapeString(username),
 		stats.Ranking,
 		stats.TotalSolved,
 		stats.TotalQuestions,
 		440*totalPercent/100,
-		stats.EasySolved,
-		440*easyPercent/100,
-		stats.MediumSolved,
-		440*mediumPercent/100,
-		stats.HardSolved,
-		440*hardPercent/100,
+		difficultyBars,
 		stats.AcceptanceRate)
 }
@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"my-realm/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+var ErrUnknownRegistry = errors.New("unknown package registry")
+
+var packageFetchers = map[string]func(ctx context.Context, name string) (*models.PackageStats, error){
+	"npm":    fetchNpmPackage,
+	"pypi":   fetchPyPIPackage,
+	"crates": fetchCratesPackage,
+	"go":     fetchGoModule,
+}
+
+// PackageRegistryLabels maps each supported registry to its display name.
+var PackageRegistryLabels = map[string]string{
+	"npm":    "npm",
+	"pypi":   "PyPI",
+	"crates": "crates.io",
+	"go":     "Go",
+}
+
+func FetchPackageStats(ctx context.Context, registry, name string) (*models.PackageStats, models.CacheInfo, error) {
+	fetch, ok := packageFetchers[registry]
+	if !ok {
+		return nil, models.CacheInfo{}, fmt.Errorf("%w: %s", ErrUnknownRegistry, registry)
+	}
+
+	return cachedFetch(ctx, "package:"+registry+":"+name, CacheTTLs["package"], func(ctx context.Context) (*models.PackageStats, error) {
+		stats, err := fetch(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		stats.Registry = registry
+		stats.Name = name
+
+		return stats, nil
+	})
+}
+
+func fetchNpmPackage(ctx context.Context, name string) (*models.PackageStats, error) {
+	// Scoped packages keep their "@" but need the slash escaped in the registry path.
+	var pkg models.NpmPackageResponse
+	if err := registryGet(ctx, "npm", upstreams.NpmRegistry+"/"+strings.Replace(name, "/", "%2F", 1), &pkg); err != nil {
+		return nil, err
+	}
+
+	var downloads models.NpmDownloadsResponse
+	if err := registryGet(ctx, "npm", upstreams.NpmDownloads+"/downloads/point/last-week/"+name, &downloads); err != nil {
+		return nil, err
+	}
+
+	return &models.PackageStats{
+		LatestVersion:   pkg.DistTags.Latest,
+		PublishedAt:     pkg.Time[pkg.DistTags.Latest],
+		WeeklyDownloads: &downloads.Downloads,
+	}, nil
+}
+
+func fetchPyPIPackage(ctx context.Context, name string) (*models.PackageStats, error) {
+	var pkg models.PyPIPackageResponse
+	if err := registryGet(ctx, "PyPI", upstreams.PyPI+"/pypi/"+url.PathEscape(name)+"/json", &pkg); err != nil {
+		return nil, err
+	}
+
+	stats := &models.PackageStats{
+		LatestVersion: pkg.Info.Version,
+	}
+	for _, file := range pkg.URLs {
+		if stats.PublishedAt.IsZero() || file.UploadTime.Before(stats.PublishedAt) {
+			stats.PublishedAt = file.UploadTime
+		}
+	}
+
+	var downloads models.PyPIStatsResponse
+	if err := registryGet(ctx, "PyPI Stats", upstreams.PyPIStats+"/api/packages/"+url.PathEscape(strings.ToLower(name))+"/recent",
+		&downloads); err != nil {
+		return nil, err
+	}
+	stats.WeeklyDownloads = &downloads.Data.LastWeek
+
+	return stats, nil
+}
+
+func fetchCratesPackage(ctx context.Context, name string) (*models.PackageStats, error) {
+	var crate models.CratesIOResponse
+	if err := registryGet(ctx, "crates.io", upstreams.CratesIO+"/api/v1/crates/"+url.PathEscape(name), &crate); err != nil {
+		return nil, err
+	}
+
+	stats := &models.PackageStats{
+		LatestVersion:  crate.Crate.MaxStableVersion,
+		TotalDownloads: &crate.Crate.Downloads,
+	}
+	if stats.LatestVersion == "" {
+		stats.LatestVersion = crate.Crate.NewestVersion
+	}
+	for _, version := range crate.Versions {
+		if version.Num == stats.LatestVersion {
+			stats.PublishedAt = version.CreatedAt
+			break
+		}
+	}
+
+	return stats, nil
+}
+
+func fetchGoModule(ctx context.Context, name string) (*models.PackageStats, error) {
+	var latest models.GoProxyLatestResponse
+	if err := registryGet(ctx, "Go module proxy", upstreams.GoProxy+"/"+escapeModulePath(name)+"/@latest", &latest); err != nil {
+		return nil, err
+	}
+
+	return &models.PackageStats{
+		LatestVersion: latest.Version,
+		PublishedAt:   latest.Time,
+	}, nil
+}
+
+func registryGet(ctx context.Context, registry, target string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	// crates.io rejects requests without a descriptive User-Agent.
+	req.Header.Set("User-Agent", "my-realm (https://github.com/risv1/my-realm)")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return requestError(registry, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(registry, resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+// escapeModulePath applies the module proxy's case encoding, where each
+// upper-case letter becomes "!" followed by its lower-case form.
+func escapeModulePath(path string) string {
+	var escaped strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			escaped.WriteByte('!')
+			escaped.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// formatCount shortens large counts the way shields.io does, e.g. 12.3k.
+func formatCount(count int64) string {
+	switch {
+	case count >= 1_000_000_000:
+		return fmt.Sprintf("%.1fB", float64(count)/1_000_000_000)
+	case count >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(count)/1_000_000)
+	case count >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(count)/1_000)
+	default:
+		return fmt.Sprint(count)
+	}
+}
+
+func optionalCount(count *int64, suffix string) string {
+	if count == nil {
+		return "n/a"
+	}
+	return formatCount(*count) + suffix
+}
+
+func GeneratePackageSVG(stats *models.PackageStats, color, background string) string {
+	themeColor, bgColor, _ := resolveTheme(color, background)
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="200" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .stat {
+                font: 500 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .stat-title {
+                font: 400 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.8;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="200"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(25, 35)">
+            <text x="0" y="0" class="title">%s</text>
+            <text x="450" y="0" class="stat-title" text-anchor="end">%s</text>
+
+            <g transform="translate(0, 40)">
+                <text class="stat-title">Latest Version</text>
+                <text x="450" y="0" class="stat" text-anchor="end">%s</text>
+
+                <text y="30" class="stat-title">Published</text>
+                <text x="450" y="30" class="stat" text-anchor="end">%s</text>
+
+                <text y="60" class="stat-title">Weekly Downloads</text>
+                <text x="450" y="60" class="stat" text-anchor="end">%s</text>
+
+                <text y="90" class="stat-title">Total Downloads</text>
+                <text x="450" y="90" class="stat" text-anchor="end">%s</text>
+            </g>
+        </g>
+    </svg>`
+
+	published := "n/a"
+	if !stats.PublishedAt.IsZero() {
+		published = stats.PublishedAt.Format("Jan 2, 2006")
+	}
+
+	return fmt.Sprintf(svgTemplate,
+		themeColor,
+		themeColor,
+		themeColor,
+		bgColor,
+		themeColor,
+		html.EscapeString(truncateText(stats.Name, 40)),
+		PackageRegistryLabels[stats.Registry],
+		html.EscapeString(stats.LatestVersion),
+		published,
+		optionalCount(stats.WeeklyDownloads, ""),
+		optionalCount(stats.TotalDownloads, ""))
+}
+
+// GeneratePackageBadgeSVG renders a flat shields.io style badge for metric,
+// which is one of "version", "weekly" or "total".
+func GeneratePackageBadgeSVG(stats *models.PackageStats, metric, color string) string {
+	themeColor := ColorSchemes[color]
+	if themeColor == "" {
+		themeColor = ColorSchemes["red"]
+	}
+
+	label := PackageRegistryLabels[stats.Registry]
+	var value string
+	switch metric {
+	case "weekly":
+		label = "downloads"
+		value = optionalCount(stats.WeeklyDownloads, "/week")
+	case "total":
+		label = "downloads"
+		value = optionalCount(stats.TotalDownloads, "")
+	default:
+		value = stats.LatestVersion
+		if !strings.HasPrefix(value, "v") {
+			value = "v" + value
+		}
+	}
+	value = html.EscapeString(value)
+
+	// Verdana 11px averages roughly 7px per character.
+	labelWidth := len(label)*7 + 10
+	valueWidth := len(value)*7 + 10
+	width := labelWidth + valueWidth
+
+	svgTemplate := `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">
+    <linearGradient id="s" x2="0" y2="100%%">
+        <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+        <stop offset="1" stop-opacity=".1"/>
+    </linearGradient>
+    <clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>
+    <g clip-path="url(#r)">
+        <rect width="%d" height="20" fill="#555"/>
+        <rect x="%d" width="%d" height="20" fill="%s"/>
+        <rect width="%d" height="20" fill="url(#s)"/>
+    </g>
+    <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+        <text x="%d" y="14">%s</text>
+        <text x="%d" y="14">%s</text>
+    </g>
+</svg>`
+
+	return fmt.Sprintf(svgTemplate,
+		width, label, value,
+		width,
+		labelWidth,
+		labelWidth, valueWidth, themeColor,
+		width,
+		labelWidth/2, label,
+		labelWidth+valueWidth/2, value)
+}
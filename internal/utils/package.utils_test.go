@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestPackageRegistriesUseUpstreams(t *testing.T) {
+	var mutex sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requested = append(requested, r.RequestURI)
+		mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.RequestURI {
+		case "/npm/@realm%2Fcards":
+			io.WriteString(w, `{"dist-tags": {"latest": "2.1.0"}, "time": {"2.1.0": "2024-05-01T00:00:00Z"}}`)
+		case "/npm-downloads/downloads/point/last-week/@realm/cards":
+			io.WriteString(w, `{"downloads": 4321}`)
+		case "/goproxy/github.com/!realm/cards/@latest":
+			io.WriteString(w, `{"Version": "v0.3.0", "Time": "2024-04-01T00:00:00Z"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	previous := upstreams
+	u := DefaultUpstreams()
+	u.NpmRegistry = server.URL + "/npm"
+	u.NpmDownloads = server.URL + "/npm-downloads"
+	u.GoProxy = server.URL + "/goproxy"
+	SetUpstreams(u)
+	t.Cleanup(func() { SetUpstreams(previous) })
+
+	stats, _, err := FetchPackageStats(context.Background(), "npm", "@realm/cards")
+	if err != nil {
+		t.Fatalf("npm: %v", err)
+	}
+	if stats.LatestVersion != "2.1.0" || stats.WeeklyDownloads == nil || *stats.WeeklyDownloads != 4321 {
+		t.Errorf("npm stats = %+v, want 2.1.0 with 4321 weekly downloads", stats)
+	}
+
+	stats, _, err = FetchPackageStats(context.Background(), "go", "github.com/Realm/cards")
+	if err != nil {
+		t.Fatalf("go: %v", err)
+	}
+	if stats.LatestVersion != "v0.3.0" || stats.PublishedAt.IsZero() {
+		t.Errorf("go stats = %+v, want v0.3.0 with its publish time", stats)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, want := range []string{"/npm/@realm%2Fcards", "/goproxy/github.com/!realm/cards/@latest"} {
+		if !slices.Contains(requested, want) {
+			t.Errorf("requests = %v, want %s", requested, want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"my-realm/internal/models"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// practiceMaxPages bounds how many pages of solutions/kata are walked per
+// profile to count completions per language.
+const practiceMaxPages = 10
+
+// practiceCardLanguages is how many languages each platform shows on the card.
+const practiceCardLanguages = 5
+
+var codewarsLanguageNames = map[string]string{
+	"javascript":   "JavaScript",
+	"typescript":   "TypeScript",
+	"coffeescript": "CoffeeScript",
+	"csharp":       "C#",
+	"cpp":          "C++",
+	"fsharp":       "F#",
+	"php":          "PHP",
+	"sql":          "SQL",
+	"ocaml":        "OCaml",
+}
+
+// FetchExercismStats builds a practice profile from the user's published
+// Exercism solutions: tracks joined are the tracks with at least one solution.
+func FetchExercismStats(ctx context.Context, username string) (*models.PracticeStats, models.CacheInfo, error) {
+	return cachedFetch(ctx, "practice:exercism:"+username, CacheTTLs["practice"], func(ctx context.Context) (*models.PracticeStats, error) {
+		return fetchExercismStats(ctx, username)
+	})
+}
+
+func fetchExercismStats(ctx context.Context, username string) (*models.PracticeStats, error) {
+	profileURL := upstreams.Exercism + "/profiles/" + url.PathEscape(username)
+
+	var profile models.ExercismProfileResponse
+	if err := practiceGet(ctx, "Exercism", profileURL, &profile); err != nil {
+		return nil, err
+	}
+
+	completed := make(map[string]int)
+	for page := 1; page <= practiceMaxPages; page++ {
+		var solutions models.ExercismSolutionsResponse
+		if err := practiceGet(ctx, "Exercism", fmt.Sprintf("%s/solutions?page=%d", profileURL, page), &solutions); err != nil {
+			return nil, err
+		}
+
+		for _, solution := range solutions.Results {
+			completed[solution.Track.Title]++
+		}
+
+		if solutions.Meta.CurrentPage >= solutions.Meta.TotalPages {
+			break
+		}
+	}
+
+	stats := &models.PracticeStats{
+		Platform:   "Exercism",
+		Username:   username,
+		Score:      parseReputation(profile.Profile.Reputation),
+		ScoreLabel: "reputation",
+		Languages:  toPracticeLanguages(completed, nil),
+	}
+	for _, count := range completed {
+		stats.TotalCompleted += count
+	}
+
+	return stats, nil
+}
+
+func FetchCodewarsStats(ctx context.Context, username string) (*models.PracticeStats, models.CacheInfo, error) {
+	return cachedFetch(ctx, "practice:codewars:"+username, CacheTTLs["practice"], func(ctx context.Context) (*models.PracticeStats, error) {
+		return fetchCodewarsStats(ctx, username)
+	})
+}
+
+func fetchCodewarsStats(ctx context.Context, username string) (*models.PracticeStats, error) {
+	userURL := upstreams.Codewars + "/users/" + url.PathEscape(username)
+
+	var user models.CodewarsUserResponse
+	if err := practiceGet(ctx, "Codewars", userURL, &user); err != nil {
+		return nil, err
+	}
+
+	completed := make(map[string]int)
+	for page := 0; page < practiceMaxPages; page++ {
+		var kata models.CodewarsCompletedResponse
+		if err := practiceGet(ctx, "Codewars", fmt.Sprintf("%s/code-challenges/completed?page=%d", userURL, page), &kata); err != nil {
+			return nil, err
+		}
+
+		for _, challenge := range kata.Data {
+			for _, language := range challenge.CompletedLanguages {
+				completed[language]++
+			}
+		}
+
+		if page+1 >= kata.TotalPages {
+			break
+		}
+	}
+
+	ranks := make(map[string]string)
+	for language, rank := range user.Ranks.Languages {
+		ranks[language] = rank.Name
+	}
+
+	stats := &models.PracticeStats{
+		Platform:       "Codewars",
+		Username:       username,
+		Rank:           user.Ranks.Overall.Name,
+		Score:          user.Honor,
+		ScoreLabel:     "honor",
+		TotalCompleted: user.CodeChallenges.TotalCompleted,
+		Languages:      toPracticeLanguages(completed, ranks),
+	}
+	for i := range stats.Languages {
+		stats.Languages[i].Name = codewarsLanguageName(stats.Languages[i].Name)
+	}
+
+	return stats, nil
+}
+
+func practiceGet(ctx context.Context, platform, target string, result any) error {
+	resp, err := httpGet(ctx, target)
+	if err != nil {
+		return requestError(platform, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(platform, resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+func toPracticeLanguages(completed map[string]int, ranks map[string]string) []models.PracticeLanguage {
+	languages := make([]models.PracticeLanguage, 0, len(completed))
+	for name, count := range completed {
+		languages = append(languages, models.PracticeLanguage{
+			Name:      name,
+			Completed: count,
+			Rank:      ranks[name],
+		})
+	}
+
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].Completed != languages[j].Completed {
+			return languages[i].Completed > languages[j].Completed
+		}
+		return languages[i].Name < languages[j].Name
+	})
+	return languages
+}
+
+// parseReputation reads Exercism's display-formatted reputation such as
+// "1,234" or "12.5k".
+func parseReputation(value string) int {
+	value = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(value), ",", ""))
+	multiplier := 1.0
+	if strings.HasSuffix(value, "k") {
+		multiplier = 1000
+		value = strings.TrimSuffix(value, "k")
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return int(number * multiplier)
+}
+
+func codewarsLanguageName(language string) string {
+	if name, ok := codewarsLanguageNames[language]; ok {
+		return name
+	}
+	if language == "" {
+		return language
+	}
+	return strings.ToUpper(language[:1]) + language[1:]
+}
+
+// GeneratePracticeSVG renders one or more practice platform profiles on a
+// single card, one section per platform, using the languages card layout.
+func GeneratePracticeSVG(profiles []*models.PracticeStats, color, background string) string {
+	themeColor, bgColor, barBgColor := resolveTheme(color, background)
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="%d" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .section-title {
+                font: 600 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.8;
+            }
+            .lang-text {
+                font: 400 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .percentage-text {
+                font: 500 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .bar-bg {
+                fill: %s;
+            }
+            .bar {
+                fill: %s;
+                opacity: 0.8;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="%d"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(30, 35)">
+            <text x="0" y="0" class="title">Practice Stats</text>
+            <g transform="translate(0, 35)">
+                %s
+            </g>
+        </g>
+    </svg>`
+
+	var sections strings.Builder
+	offset := 0
+	for _, profile := range profiles {
+		summary := fmt.Sprintf("%d completed · %d %s", profile.TotalCompleted, profile.Score, profile.ScoreLabel)
+		if profile.Rank != "" {
+			summary = profile.Rank + " · " + summary
+		}
+
+		sections.WriteString(fmt.Sprintf(`
+            <text x="0" y="%d" class="section-title">@%s on %s</text>
+            <text x="440" y="%d" class="percentage-text" text-anchor="end">%s</text>`,
+			offset, html.EscapeString(profile.Username), profile.Platform, offset, html.EscapeString(summary)))
+		offset += 30
+
+		total := 0
+		for _, language := range profile.Languages {
+			total += language.Completed
+		}
+
+		languages := profile.Languages
+		if len(languages) > practiceCardLanguages {
+			languages = languages[:practiceCardLanguages]
+		}
+		for _, language := range languages {
+			label := fmt.Sprintf("%d solved", language.Completed)
+			if language.Rank != "" {
+				label = language.Rank + " · " + label
+			}
+			percentage := 0.0
+			if total > 0 {
+				percentage = float64(language.Completed) / float64(total) * 100
+			}
+			sections.WriteString(horizontalBar(offset, language.Name, label, percentage))
+			offset += 40
+		}
+		offset += 15
+	}
+
+	height := 70 + offset
+
+	return fmt.Sprintf(svgTemplate,
+		height,
+		themeColor,
+		themeColor,
+		themeColor,
+		themeColor,
+		barBgColor,
+		themeColor,
+		height,
+		bgColor,
+		themeColor,
+		sections.String())
+}
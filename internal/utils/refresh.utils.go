@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand/v2"
+	"my-realm/internal/models"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// refreshTarget is a cached entry requests have asked for, with what it takes
+// to refresh it.
+type refreshTarget struct {
+	key      string
+	ttl      time.Duration
+	requests float64
+	// refresh fetches the entry again unless it is younger than minAge.
+	refresh func(ctx context.Context, minAge time.Duration) error
+}
+
+// requestHalfLife is how long it takes an entry's request count to halve, so
+// entries nobody asks for anymore cool down and are eventually forgotten.
+const requestHalfLife = time.Hour
+
+// maxTargetsPerHottest bounds how many entries are tracked, as a multiple of
+// how many are kept warm.
+const maxTargetsPerHottest = 10
+
+var (
+	tracking     atomic.Bool
+	targets      = make(map[string]*refreshTarget)
+	targetsMutex sync.Mutex
+)
+
+// fetchedKeysKey is the context key of the fetchedKeys a request notes its
+// cached entries in.
+type fetchedKeysKey struct{}
+
+type fetchedKeys struct {
+	mutex sync.Mutex
+	keys  []string
+}
+
+// WithFetchedKeys returns a context that notes the key of every cached entry
+// fetched with it, and a function listing them, so a rendered card can count
+// requests for its data again with TrackRequests when it is served from the
+// card cache.
+func WithFetchedKeys(ctx context.Context) (context.Context, func() []string) {
+	fetched := &fetchedKeys{}
+	return context.WithValue(ctx, fetchedKeysKey{}, fetched), func() []string {
+		fetched.mutex.Lock()
+		defer fetched.mutex.Unlock()
+		return slices.Clone(fetched.keys)
+	}
+}
+
+// trackRequest counts a request for the entry under key while the refresher
+// is running, and notes key in ctx for WithFetchedKeys.
+func trackRequest(ctx context.Context, key string, ttl time.Duration, refresh func(ctx context.Context, minAge time.Duration) error) {
+	if fetched, ok := ctx.Value(fetchedKeysKey{}).(*fetchedKeys); ok {
+		fetched.mutex.Lock()
+		if !slices.Contains(fetched.keys, key) {
+			fetched.keys = append(fetched.keys, key)
+		}
+		fetched.mutex.Unlock()
+	}
+
+	if !tracking.Load() {
+		return
+	}
+
+	targetsMutex.Lock()
+	defer targetsMutex.Unlock()
+
+	target, exists := targets[key]
+	if !exists {
+		target = &refreshTarget{key: key, ttl: ttl, refresh: refresh}
+		targets[key] = target
+	}
+	target.requests++
+}
+
+// TrackRequests counts a request for each entry under keys that is already
+// tracked, for requests served without fetching them, such as cached cards.
+func TrackRequests(keys ...string) {
+	if !tracking.Load() {
+		return
+	}
+
+	targetsMutex.Lock()
+	defer targetsMutex.Unlock()
+
+	for _, key := range keys {
+		if target, exists := targets[key]; exists {
+			target.requests++
+		}
+	}
+}
+
+// RunRefresher keeps the opts.Hottest most requested entries warm until ctx
+// is done, refreshing each shortly before its TTL runs out so requests for it
+// never wait on the upstream. It returns once ctx is done and the refreshes
+// in progress have finished.
+func RunRefresher(ctx context.Context, opts models.RefreshOptions) {
+	if opts.Hottest <= 0 {
+		return
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	tracking.Store(true)
+	defer tracking.Store(false)
+
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	slots := make(chan struct{}, opts.Concurrency)
+	decay := math.Pow(0.5, float64(opts.Interval)/float64(requestHalfLife))
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		budget := GitHubRateLimit().Remaining - opts.GitHubReserve
+		for _, target := range hottestTargets(opts.Hottest, decay) {
+			if !refreshDue(target, opts.Interval) {
+				continue
+			}
+			if strings.HasPrefix(target.key, "github:") {
+				if GitHubRateLimit().Known() && budget <= 0 {
+					continue
+				}
+				budget--
+			}
+
+			running.Add(1)
+			go func() {
+				defer running.Done()
+
+				// Spread the refreshes over the interval rather than sending
+				// them to the upstreams all at once.
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(rand.N(opts.Interval / 2)):
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case slots <- struct{}{}:
+				}
+				defer func() { <-slots }()
+
+				if err := target.refresh(ctx, target.ttl-refreshLead(opts.Interval, target.ttl)); err != nil {
+					log.Printf("error refreshing %s in the background: %v", target.key, err)
+				}
+			}()
+		}
+	}
+}
+
+// hottestTargets returns the n most requested targets and multiplies the
+// request counts of all of them by decay.
+func hottestTargets(n int, decay float64) []*refreshTarget {
+	targetsMutex.Lock()
+	defer targetsMutex.Unlock()
+
+	ranked := make([]*refreshTarget, 0, len(targets))
+	for _, target := range targets {
+		ranked = append(ranked, target)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].requests > ranked[j].requests
+	})
+
+	hottest := make([]*refreshTarget, 0, n)
+	for i, target := range ranked {
+		if i < n {
+			copied := *target
+			hottest = append(hottest, &copied)
+		}
+
+		// Keep the map bounded, forgetting the coldest targets.
+		target.requests *= decay
+		if target.requests < 0.5 || i >= n*maxTargetsPerHottest {
+			delete(targets, target.key)
+		}
+	}
+	return hottest
+}
+
+// refreshDue reports whether target could expire before the refresh after
+// the next check runs.
+func refreshDue(target *refreshTarget, interval time.Duration) bool {
+	var cached struct {
+		Timestamp time.Time `json:"timestamp"`
+	}
+	if !loadCached(target.key, &cached) {
+		return true
+	}
+	return time.Since(cached.Timestamp) >= target.ttl-refreshLead(interval, target.ttl)
+}
+
+// refreshLead is how long before expiring an entry is refreshed: a refresh
+// can start up to half an interval after the check that finds it due, which
+// happens up to an interval after the entry became due. It is at most half of
+// ttl, so an entry that expires within a couple of intervals is refreshed
+// when it is half way there rather than at every check.
+func refreshLead(interval, ttl time.Duration) time.Duration {
+	return min(2*interval, ttl/2)
+}
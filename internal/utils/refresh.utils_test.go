@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"context"
+	"my-realm/internal/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func startTestRefresher(t *testing.T, opts models.RefreshOptions) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RunRefresher(ctx, opts)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		targetsMutex.Lock()
+		clear(targets)
+		targetsMutex.Unlock()
+	})
+
+	for !tracking.Load() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRefresherKeepsHotEntriesFresh(t *testing.T) {
+	useTestCache(t)
+	startTestRefresher(t, models.RefreshOptions{Hottest: 1, Interval: 20 * time.Millisecond, Concurrency: 1})
+
+	var hotCalls, coldCalls atomic.Int64
+	const ttl = 100 * time.Millisecond
+	for i := 0; i < 5; i++ {
+		if _, _, err := cachedFetch(context.Background(), "test:hot", ttl, func(context.Context) (int64, error) { return hotCalls.Add(1), nil }); err != nil {
+			t.Fatalf("hot fetch: %v", err)
+		}
+	}
+	if _, _, err := cachedFetch(context.Background(), "test:cold", ttl, func(context.Context) (int64, error) { return coldCalls.Add(1), nil }); err != nil {
+		t.Fatalf("cold fetch: %v", err)
+	}
+
+	time.Sleep(4 * ttl)
+
+	if hotCalls.Load() < 2 {
+		t.Errorf("hot entry fetched %d times, want background refreshes", hotCalls.Load())
+	}
+	if coldCalls.Load() != 1 {
+		t.Errorf("cold entry fetched %d times, want only the first request", coldCalls.Load())
+	}
+	if _, info, _ := cachedFetch(context.Background(), "test:hot", ttl, func(context.Context) (int64, error) { return hotCalls.Add(1), nil }); info.Stale {
+		t.Errorf("hot entry is stale, want it refreshed before expiring")
+	}
+}
+
+func TestRefresherRespectsGitHubBudget(t *testing.T) {
+	useTestCache(t)
+	recordQuota("test-token", githubGraphQL, 10, time.Now().Add(time.Hour))
+	t.Cleanup(func() {
+		tokensMutex.Lock()
+		clear(tokenStates)
+		tokensMutex.Unlock()
+	})
+
+	startTestRefresher(t, models.RefreshOptions{Hottest: 1, Interval: 20 * time.Millisecond, Concurrency: 1, GitHubReserve: 10})
+
+	var calls atomic.Int64
+	const ttl = 50 * time.Millisecond
+	if _, _, err := cachedFetch(context.Background(), "github::octocat", ttl, func(context.Context) (int64, error) { return calls.Add(1), nil }); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	time.Sleep(4 * ttl)
+
+	if calls.Load() != 1 {
+		t.Errorf("entry fetched %d times with no GitHub budget left, want 1", calls.Load())
+	}
+}
+
+func TestRefreshDueWithIntervalLongerThanHalfTheTTL(t *testing.T) {
+	useTestCache(t)
+
+	const ttl = 100 * time.Millisecond
+	if _, _, err := cachedFetch(context.Background(), "test:short", ttl, func(context.Context) (int, error) { return 1, nil }); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	target := &refreshTarget{key: "test:short", ttl: ttl}
+
+	if refreshDue(target, time.Minute) {
+		t.Error("entry fetched just now is due, want it left until half its TTL")
+	}
+	time.Sleep(ttl / 2)
+	if !refreshDue(target, time.Minute) {
+		t.Error("entry half way to expiring is not due")
+	}
+}
+
+func TestTrackRequestsCountsFetchedKeys(t *testing.T) {
+	useTestCache(t)
+	startTestRefresher(t, models.RefreshOptions{Hottest: 1, Interval: time.Hour, Concurrency: 1})
+
+	ctx, fetchedKeys := WithFetchedKeys(context.Background())
+	for _, key := range []string{"test:a", "test:b", "test:a"} {
+		if _, _, err := cachedFetch(ctx, key, time.Minute, func(context.Context) (int, error) { return 1, nil }); err != nil {
+			t.Fatalf("fetch %s: %v", key, err)
+		}
+	}
+	keys := fetchedKeys()
+	if len(keys) != 2 || keys[0] != "test:a" || keys[1] != "test:b" {
+		t.Fatalf("fetched keys = %v, want test:a and test:b once each", keys)
+	}
+
+	// A card served from the card cache counts as a request for its data.
+	TrackRequests(keys...)
+
+	targetsMutex.Lock()
+	defer targetsMutex.Unlock()
+	if targets["test:a"].requests != 3 || targets["test:b"].requests != 2 {
+		t.Errorf("requests = %v and %v, want 3 and 2", targets["test:a"].requests, targets["test:b"].requests)
+	}
+}
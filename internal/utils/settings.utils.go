@@ -0,0 +1,20 @@
+package utils
+
+import "my-realm/internal/config"
+
+// Configure applies the tunables of env to the providers, the caches and the
+// upstream client, keeping the defaults of settings left unset. It is meant
+// to be called once at startup, before the server starts handling requests.
+func Configure(env *config.Env) {
+	if maxStaleness, ok := env.MaxStaleness(); ok {
+		MaxStaleness = maxStaleness
+	}
+	if cardTTL, ok := env.RenderedCardTTL(); ok {
+		CardTTL = cardTTL
+	}
+	CacheTTLs = env.CacheTTLs(CacheTTLs)
+	DefaultUpstreamTimeout, UpstreamTimeouts = env.UpstreamTimeouts(DefaultUpstreamTimeout, UpstreamTimeouts)
+
+	SetUpstreams(env.Upstreams(DefaultUpstreams()))
+	SetHTTPClient(NewHTTPClient(env.TransportOptions(DefaultTransportOptions())))
+}
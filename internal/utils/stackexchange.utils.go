@@ -0,0 +1,321 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"my-realm/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stackExchangeAnswerPages bounds how many pages of answers are scanned to
+// compute the accepted answer ratio.
+const stackExchangeAnswerPages = 3
+
+var (
+	// stackExchangeBackoff records, per API method, when the backoff requested
+	// by the last response expires. Calls made before then are refused.
+	stackExchangeBackoff      = make(map[string]time.Time)
+	stackExchangeBackoffMutex sync.Mutex
+)
+
+var ErrStackExchangeBackoff = errors.New("stack exchange asked us to back off")
+
+var stackExchangeSiteNames = map[string]string{
+	"stackoverflow": "Stack Overflow",
+	"superuser":     "Super User",
+	"serverfault":   "Server Fault",
+	"askubuntu":     "Ask Ubuntu",
+	"math":          "Mathematics",
+}
+
+func FetchStackExchangeStats(ctx context.Context, userID, site, key string) (*models.StackExchangeStats, models.CacheInfo, error) {
+	return cachedFetch(ctx, "stackexchange:"+site+":"+userID, CacheTTLs["stackexchange"], func(ctx context.Context) (*models.StackExchangeStats, error) {
+		return fetchStackExchangeStats(ctx, userID, site, key)
+	})
+}
+
+func fetchStackExchangeStats(ctx context.Context, userID, site, key string) (*models.StackExchangeStats, error) {
+	params := url.Values{}
+	params.Set("site", site)
+	if key != "" {
+		params.Set("key", key)
+	}
+
+	var users []models.StackExchangeUser
+	if _, err := stackExchangeGet(ctx, "users", "/users/"+userID, params, &users); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, notFoundError("Stack Exchange", fmt.Errorf("user %s not found on %s", userID, site))
+	}
+
+	stats := &models.StackExchangeStats{
+		DisplayName: html.UnescapeString(users[0].DisplayName),
+		Site:        site,
+		Reputation:  users[0].Reputation,
+		Badges:      users[0].BadgeCounts,
+		TopTags:     []models.StackExchangeTag{},
+	}
+
+	totalParams := withParam(params, "filter", "total")
+	answers, err := stackExchangeGet(ctx, "users/answers", "/users/"+userID+"/answers", totalParams, nil)
+	if err != nil {
+		return nil, err
+	}
+	stats.AnswerCount = answers.Total
+
+	questions, err := stackExchangeGet(ctx, "users/questions", "/users/"+userID+"/questions", totalParams, nil)
+	if err != nil {
+		return nil, err
+	}
+	stats.QuestionCount = questions.Total
+
+	ratio, err := fetchStackExchangeAcceptedRatio(ctx, userID, params)
+	if err != nil {
+		return nil, err
+	}
+	stats.AcceptedAnswerRatio = ratio
+
+	var tags []models.StackExchangeTopTag
+	if _, err := stackExchangeGet(ctx, "users/top-tags", "/users/"+userID+"/top-tags",
+		withParam(params, "pagesize", "5"), &tags); err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		stats.TopTags = append(stats.TopTags, models.StackExchangeTag{
+			Name:          tag.TagName,
+			AnswerCount:   tag.AnswerCount,
+			AnswerScore:   tag.AnswerScore,
+			QuestionCount: tag.QuestionCount,
+		})
+	}
+
+	return stats, nil
+}
+
+// fetchStackExchangeAcceptedRatio returns the percentage of the user's most
+// recent answers (up to stackExchangeAnswerPages pages) that were accepted.
+func fetchStackExchangeAcceptedRatio(ctx context.Context, userID string, params url.Values) (float64, error) {
+	scanned, accepted := 0, 0
+	for page := 1; page <= stackExchangeAnswerPages; page++ {
+		pageParams := withParam(params, "pagesize", "100")
+		pageParams.Set("page", fmt.Sprint(page))
+		pageParams.Set("sort", "activity")
+
+		var answers []models.StackExchangeAnswer
+		resp, err := stackExchangeGet(ctx, "users/answers", "/users/"+userID+"/answers", pageParams, &answers)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, answer := range answers {
+			scanned++
+			if answer.IsAccepted {
+				accepted++
+			}
+		}
+
+		if !resp.HasMore {
+			break
+		}
+	}
+
+	if scanned == 0 {
+		return 0, nil
+	}
+	return float64(accepted) / float64(scanned) * 100, nil
+}
+
+// stackExchangeGet calls a Stack Exchange API method, honoring and recording
+// the backoff field, recording the quota, and decodes the wrapper's items into items when non-nil.
+func stackExchangeGet(ctx context.Context, method, path string, params url.Values, items any) (*models.StackExchangeResponse, error) {
+	stackExchangeBackoffMutex.Lock()
+	until := stackExchangeBackoff[method]
+	stackExchangeBackoffMutex.Unlock()
+	if wait := time.Until(until); wait > 0 {
+		return nil, fmt.Errorf("%w: %s for another %s", ErrStackExchangeBackoff, method, wait.Round(time.Second))
+	}
+
+	resp, err := httpGet(ctx, upstreams.StackExchange+path+"?"+params.Encode())
+	if err != nil {
+		return nil, requestError("Stack Exchange", err)
+	}
+	defer resp.Body.Close()
+
+	// Errors the API reports itself come as JSON with a 4xx status and are
+	// read below; 5xx responses come from whatever is in front of it.
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, statusError("Stack Exchange", resp)
+	}
+
+	var result models.StackExchangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	// Every response carries the quota left for the day, shared by all methods.
+	if result.QuotaMax > 0 {
+		recordUpstreamQuota("stackexchange", result.QuotaRemaining, result.QuotaMax)
+	}
+
+	if result.Backoff > 0 {
+		stackExchangeBackoffMutex.Lock()
+		stackExchangeBackoff[method] = time.Now().Add(time.Duration(result.Backoff) * time.Second)
+		stackExchangeBackoffMutex.Unlock()
+	}
+
+	if result.ErrorID != 0 {
+		if result.ErrorName == "throttle_violation" {
+			return nil, fmt.Errorf("%w: %s", ErrStackExchangeBackoff, result.ErrorMessage)
+		}
+		return nil, fmt.Errorf("stack exchange API error: %s", result.ErrorMessage)
+	}
+
+	if items != nil && len(result.Items) > 0 {
+		if err := json.Unmarshal(result.Items, items); err != nil {
+			return nil, fmt.Errorf("error decoding items: %w", err)
+		}
+	}
+
+	return &result, nil
+}
+
+func withParam(params url.Values, key, value string) url.Values {
+	copied := url.Values{}
+	for k, v := range params {
+		copied[k] = append([]string(nil), v...)
+	}
+	copied.Set(key, value)
+	return copied
+}
+
+func stackExchangeSiteName(site string) string {
+	if name, ok := stackExchangeSiteNames[site]; ok {
+		return name
+	}
+	if site == "" {
+		return site
+	}
+	return strings.ToUpper(site[:1]) + site[1:]
+}
+
+func GenerateStackExchangeStatsSVG(stats *models.StackExchangeStats, color, background string) string {
+	themeColor, bgColor, barBgColor := resolveTheme(color, background)
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="%d" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .stat {
+                font: 500 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .stat-title {
+                font: 400 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.8;
+            }
+            .rank {
+                font: 700 24px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .progress-bar-bg {
+                fill: %s;
+                opacity: 0.2;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="%d"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(25, 35)">
+            <text x="0" y="0" class="title">%s's %s Stats</text>
+
+            <g transform="translate(0, 55)">
+                <text class="stat-title">Reputation</text>
+                <text x="0" y="25" class="rank">%d</text>
+                <g transform="translate(250, 18)">
+                    <circle cx="6" cy="0" r="6" fill="rgb(255, 204, 1)"/>
+                    <text x="18" y="5" class="stat">%d</text>
+                    <circle cx="76" cy="0" r="6" fill="rgb(180, 184, 188)"/>
+                    <text x="88" y="5" class="stat">%d</text>
+                    <circle cx="146" cy="0" r="6" fill="rgb(209, 166, 132)"/>
+                    <text x="158" y="5" class="stat">%d</text>
+                </g>
+            </g>
+
+            <g transform="translate(0, 120)">
+                <text class="stat-title">Answers</text>
+                <text x="430" y="0" class="stat" text-anchor="end">%d</text>
+
+                <text y="30" class="stat-title">Questions</text>
+                <text x="430" y="30" class="stat" text-anchor="end">%d</text>
+            </g>
+
+            <g transform="translate(0, 180)">
+                %s
+            </g>
+
+            <g transform="translate(0, 235)">
+                <text class="stat-title">Top Tags</text>
+                %s
+            </g>
+        </g>
+    </svg>`
+
+	acceptedBar := progressBar(0, "Accepted Answers", fmt.Sprintf("%.1f%%", stats.AcceptedAnswerRatio), themeColor,
+		stats.AcceptedAnswerRatio/100)
+
+	var tagRows strings.Builder
+	for i, tag := range stats.TopTags {
+		tagRows.WriteString(fmt.Sprintf(`
+                <text x="0" y="%d" class="stat">%s</text>
+                <text x="430" y="%d" class="stat-title" text-anchor="end">%d answers · score %d</text>`,
+			(i+1)*25, html.EscapeString(tag.Name), (i+1)*25, tag.AnswerCount, tag.AnswerScore))
+	}
+
+	height := 290 + len(stats.TopTags)*25
+
+	return fmt.Sprintf(svgTemplate,
+		height,
+		themeColor,
+		themeColor,
+		themeColor,
+		themeColor,
+		barBgColor,
+		height,
+		bgColor,
+		themeColor,
+		html.EscapeString(stats.DisplayName),
+		stackExchangeSiteName(stats.Site),
+		stats.Reputation,
+		stats.Badges.Gold,
+		stats.Badges.Silver,
+		stats.Badges.Bronze,
+		stats.AnswerCount,
+		stats.QuestionCount,
+		acceptedBar,
+		tagRows.String())
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"my-realm/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+type upstreamCounters struct {
+	cache   models.CacheStats
+	hour    time.Time
+	fetches int64
+	quota   *models.UpstreamQuota
+}
+
+var (
+	upstreamStats      = make(map[string]*upstreamCounters)
+	upstreamStatsMutex sync.Mutex
+)
+
+// upstreamName is the upstream a cache key belongs to, e.g. "github" for
+// "github:repos:alice" and "codewars" for "practice:codewars:alice".
+func upstreamName(key string) string {
+	parts := strings.SplitN(key, ":", 3)
+	switch {
+	case len(parts) < 2:
+		return parts[0]
+	case parts[0] == "practice", parts[0] == "package":
+		return parts[1]
+	case parts[0] == "feed" && parts[1] != "url":
+		return parts[1]
+	default:
+		return parts[0]
+	}
+}
+
+// upstreamCountersFor returns the counters of the upstream key belongs to.
+// The caller must hold upstreamStatsMutex.
+func upstreamCountersFor(key string) *upstreamCounters {
+	name := upstreamName(key)
+	counters, exists := upstreamStats[name]
+	if !exists {
+		counters = &upstreamCounters{}
+		upstreamStats[name] = counters
+	}
+	return counters
+}
+
+// countCacheLookup counts a lookup of key, served from the cache or not.
+func countCacheLookup(key string, hit bool) {
+	upstreamStatsMutex.Lock()
+	defer upstreamStatsMutex.Unlock()
+
+	counters := upstreamCountersFor(key)
+	if hit {
+		counters.cache.Hits++
+	} else {
+		counters.cache.Misses++
+	}
+}
+
+// countFetch counts a fetch of key that goes to its upstream.
+func countFetch(key string) {
+	upstreamStatsMutex.Lock()
+	defer upstreamStatsMutex.Unlock()
+
+	counters := upstreamCountersFor(key)
+	if hour := time.Now().Truncate(time.Hour); !counters.hour.Equal(hour) {
+		counters.hour = hour
+		counters.fetches = 0
+	}
+	counters.fetches++
+}
+
+// recordUpstreamQuota records the quota upstream reported in its last response.
+func recordUpstreamQuota(upstream string, remaining, max int) {
+	upstreamStatsMutex.Lock()
+	defer upstreamStatsMutex.Unlock()
+
+	upstreamCountersFor(upstream).quota = &models.UpstreamQuota{
+		Remaining: remaining,
+		Max:       max,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// UpstreamStatuses returns the cache lookups, fetches and quota of every
+// upstream requested since the server started, and which upstreams are held
+// back by their circuit.
+func UpstreamStatuses() map[string]models.UpstreamStatus {
+	upstreamStatsMutex.Lock()
+	defer upstreamStatsMutex.Unlock()
+
+	hour := time.Now().Truncate(time.Hour)
+	result := make(map[string]models.UpstreamStatus, len(upstreamStats))
+	for name, counters := range upstreamStats {
+		status := models.UpstreamStatus{Cache: counters.cache.WithHitRatio()}
+		if counters.quota != nil {
+			quota := *counters.quota
+			status.Quota = &quota
+		}
+		if counters.hour.Equal(hour) {
+			status.Fetches = counters.fetches
+		}
+		result[name] = status
+	}
+
+	if transport, ok := httpClient.Transport.(*resilientTransport); ok {
+		for name, state := range transport.circuitStates() {
+			status := result[name]
+			status.Circuit = state
+			result[name] = status
+		}
+	}
+	return result
+}
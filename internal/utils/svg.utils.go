@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resolveTheme maps the color and background query values onto the palettes
+// in ColorSchemes and BackgroundSchemes, falling back to red on black.
+func resolveTheme(color, background string) (themeColor, bgColor, barBgColor string) {
+	themeColor = ColorSchemes[color]
+	if themeColor == "" {
+		themeColor = ColorSchemes["red"]
+	}
+
+	bgColor = BackgroundSchemes[background]
+	if bgColor == "" {
+		bgColor = BackgroundSchemes["black"]
+	}
+
+	barBgColor = neutral
+	if background == white {
+		barBgColor = gray
+	}
+
+	return themeColor, bgColor, barBgColor
+}
+
+// progressBar renders a labelled 440px bar at the given vertical offset, as
+// used for the LeetCode difficulty breakdown. fraction is clamped to [0, 1],
+// with NaN (e.g. a zero denominator) treated as empty.
+func progressBar(y int, label, value, fill string, fraction float64) string {
+	if !(fraction > 0) {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	return fmt.Sprintf(`
+                <g transform="translate(0, %d)">
+                    <text class="stat-title" style="fill: %s">%s</text>
+                    <text x="430" y="0" class="stat" text-anchor="end">%s</text>
+                    <rect x="0" y="10" width="440" height="8" rx="4" class="progress-bar-bg"/>
+                    <rect x="0" y="10" width="%.1f" height="8" rx="4" style="fill: %s; opacity: 0.8;"/>
+                </g>`, y, fill, label, value, 440*fraction, fill)
+}
+
+// horizontalBar renders one row of the languages card: a name, a right-aligned
+// label and a 440px bar filled to percentage (0-100).
+func horizontalBar(y int, name, label string, percentage float64) string {
+	return fmt.Sprintf(`
+            <g transform="translate(0, %d)">
+                <text x="0" y="0" class="lang-text">%s</text>
+                <text x="440" y="0" class="percentage-text" text-anchor="end">%s</text>
+                <g transform="translate(0, 10)">
+                    <rect 
+                        x="0" 
+                        y="0" 
+                        width="440" 
+                        height="8" 
+                        rx="4" 
+                        class="bar-bg"
+                    />
+                    <rect 
+                        x="0" 
+                        y="0" 
+                        width="%.1f" 
+                        height="8" 
+                        rx="4" 
+                        class="bar"
+                    />
+                </g>
+            </g>
+        `, y, html.EscapeString(name), html.EscapeString(label), 440*(percentage/100))
+}
+
+var svgSizePattern = regexp.MustCompile(`<svg[^>]*\swidth="(\d+)"[^>]*\sheight="(\d+)"`)
+
+// AddUpdatedFootnote writes when the card's data was fetched into its bottom
+// right corner. Cards too short to have room for it, such as badges, are
+// returned unchanged.
+func AddUpdatedFootnote(svg string, fetchedAt time.Time, color string) string {
+	match := svgSizePattern.FindStringSubmatch(svg)
+	end := strings.LastIndex(svg, "</svg>")
+	if match == nil || end < 0 {
+		return svg
+	}
+
+	width, _ := strconv.Atoi(match[1])
+	height, _ := strconv.Atoi(match[2])
+	if height < 100 {
+		return svg
+	}
+
+	themeColor, _, _ := resolveTheme(color, "")
+	footnote := fmt.Sprintf(`    <text x="%d" y="%d" text-anchor="end" `+
+		`style="font: 400 10px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif; fill: %s; opacity: 0.6;">Updated %s</text>
+    `, width-12, height-8, themeColor, fetchedAt.UTC().Format("Jan 2, 15:04 UTC"))
+
+	return svg[:end] + footnote + svg[end:]
+}
+
+// GenerateErrorSVG draws a card in the requested theme saying why the card
+// asked for could not be drawn, so READMEs show the reason instead of a
+// broken image.
+func GenerateErrorSVG(title, message, color, background string) string {
+	themeColor, bgColor, _ := resolveTheme(color, background)
+
+	lines := wrapText(message, 60)
+	height := 75 + 20*len(lines)
+
+	var body strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&body, `
+            <text x="0" y="%d" class="message">%s</text>`, 30+20*i, html.EscapeString(line))
+	}
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="%d" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .message {
+                font: 400 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="%d"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(25, 35)">
+            <text x="0" y="0" class="title">%s</text>%s
+        </g>
+    </svg>`
+
+	return fmt.Sprintf(svgTemplate,
+		height,
+		themeColor,
+		themeColor,
+		height,
+		bgColor,
+		themeColor,
+		html.EscapeString(title),
+		body.String())
+}
+
+// wrapText splits text into lines of at most width characters, breaking
+// between words.
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
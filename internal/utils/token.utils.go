@@ -0,0 +1,273 @@
+package utils
+
+import (
+	"context"
+	"math"
+	"my-realm/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHub keeps separate quotas for REST ("core") and GraphQL calls.
+const (
+	githubCore    = "core"
+	githubGraphQL = "graphql"
+)
+
+const (
+	// invalidTokenPause is how long a token GitHub rejected is left out.
+	invalidTokenPause = time.Hour
+	// secondaryLimitPause is how long a token is left out after a secondary
+	// rate limit that came without a Retry-After.
+	secondaryLimitPause = time.Minute
+)
+
+type tokenQuota struct {
+	token    string
+	resource string
+}
+
+// tokenState is what GitHub last told us about a token's quota for one
+// resource, and how many requests it has been used for since.
+type tokenState struct {
+	remaining int
+	reset     time.Time
+	requests  int64
+}
+
+var (
+	tokenStates  = make(map[tokenQuota]*tokenState)
+	pausedTokens = make(map[string]time.Time)
+	tokensMutex  sync.Mutex
+)
+
+// pickToken returns the token with the most quota left for resource, leaving
+// out tried tokens and paused ones unless every token is paused. A token
+// GitHub hasn't reported on yet, or whose window has reset since, counts as
+// having its full quota.
+func pickToken(tokens []string, resource string, tried map[string]bool) (string, bool) {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	now := time.Now()
+	best, bestRemaining := "", -1
+	for _, token := range tokens {
+		if tried[token] || now.Before(pausedTokens[token]) {
+			continue
+		}
+
+		remaining := math.MaxInt
+		if state, ok := tokenStates[tokenQuota{token, resource}]; ok && state.remaining >= 0 && now.Before(state.reset) {
+			remaining = state.remaining
+		}
+		if remaining > bestRemaining {
+			best, bestRemaining = token, remaining
+		}
+	}
+	if best != "" || len(tried) > 0 {
+		return best, best != ""
+	}
+
+	// Every token is paused. Rather than failing outright, try the one that
+	// comes back first.
+	for _, token := range tokens {
+		if best == "" || pausedTokens[token].Before(pausedTokens[best]) {
+			best = token
+		}
+	}
+	return best, best != ""
+}
+
+// tokenStateFor returns the state of token for resource. The caller must
+// hold tokensMutex.
+func tokenStateFor(token, resource string) *tokenState {
+	state, exists := tokenStates[tokenQuota{token, resource}]
+	if !exists {
+		state = &tokenState{remaining: -1}
+		tokenStates[tokenQuota{token, resource}] = state
+	}
+	return state
+}
+
+// recordQuota keeps the quota GitHub reported for token.
+func recordQuota(token, resource string, remaining int, reset time.Time) {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	state := tokenStateFor(token, resource)
+	state.remaining, state.reset = remaining, reset
+}
+
+// countTokenRequest counts a request sent with token, starting over once the
+// quota window GitHub reported has reset.
+func countTokenRequest(token, resource string) {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	state := tokenStateFor(token, resource)
+	if !state.reset.IsZero() && time.Now().After(state.reset) {
+		state.remaining, state.reset, state.requests = -1, time.Time{}, 0
+	}
+	state.requests++
+}
+
+// recordRateLimitHeaders keeps the X-RateLimit headers of a GitHub response.
+func recordRateLimitHeaders(token, resource string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	if r := header.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+	recordQuota(token, resource, remaining, time.Unix(reset, 0))
+}
+
+func pauseToken(token string, until time.Time) {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	pausedTokens[token] = until
+}
+
+// githubDo sends the request newRequest builds with the healthiest of tokens,
+// moving on to the next token when GitHub rejects one or rate limits it, and
+// returns the response along with the token it was sent with. Without tokens
+// the request is sent unauthenticated. When every token fails, the last
+// response is returned for the caller to report; once ctx is done, the
+// remaining tokens are not tried.
+func githubDo(ctx context.Context, tokens []string, resource string, newRequest func() (*http.Request, error)) (*http.Response, string, error) {
+	tried := make(map[string]bool)
+	for {
+		req, err := newRequest()
+		if err != nil {
+			return nil, "", err
+		}
+
+		token, ok := pickToken(tokens, resource, tried)
+		if ok {
+			req.Header.Set("Authorization", "Bearer "+token)
+			tried[token] = true
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, token, requestError("GitHub", err)
+		}
+		if !ok {
+			return resp, "", nil
+		}
+		countTokenRequest(token, resource)
+		recordRateLimitHeaders(token, resource, resp.Header)
+
+		pauseUntil, rejected := tokenRejection(resp)
+		if !rejected {
+			return resp, token, nil
+		}
+		pauseToken(token, pauseUntil)
+
+		if _, more := pickToken(tokens, resource, tried); !more {
+			return resp, token, nil
+		}
+		resp.Body.Close()
+		if err := ctx.Err(); err != nil {
+			return nil, token, requestError("GitHub", err)
+		}
+	}
+}
+
+// tokenRejection reports whether resp shows the token it was sent with is
+// unusable for now, and until when.
+func tokenRejection(resp *http.Response) (time.Time, bool) {
+	now := time.Now()
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return now.Add(invalidTokenPause), true
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second), true
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				return time.Unix(reset, 0), true
+			}
+			return now.Add(secondaryLimitPause), true
+		}
+		// A 403 without rate limit headers is a permission error that no
+		// other token would fix either.
+		return time.Time{}, false
+	default:
+		return time.Time{}, false
+	}
+}
+
+// GitHubRateLimit returns the GraphQL quota left across every token GitHub
+// has reported on, with the earliest reset among them.
+func GitHubRateLimit() models.RateLimit {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	limit := models.RateLimit{Remaining: -1}
+	now := time.Now()
+	for quota, state := range tokenStates {
+		if quota.resource != githubGraphQL || state.remaining < 0 || !now.Before(state.reset) {
+			continue
+		}
+		if limit.Remaining < 0 {
+			limit.Remaining = 0
+		}
+		limit.Remaining += state.remaining
+		if limit.Reset.IsZero() || state.reset.Before(limit.Reset) {
+			limit.Reset = state.reset
+		}
+	}
+	return limit
+}
+
+// GitHubTokenStatuses reports the quota of each of tokens for both resources,
+// with the tokens redacted.
+func GitHubTokenStatuses(instance string, tokens []string) []models.TokenStatus {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	now := time.Now()
+	var statuses []models.TokenStatus
+	for _, token := range tokens {
+		for _, resource := range []string{githubCore, githubGraphQL} {
+			status := models.TokenStatus{
+				Instance:  instance,
+				Token:     redactToken(token),
+				Resource:  resource,
+				Remaining: -1,
+			}
+			if state, ok := tokenStates[tokenQuota{token, resource}]; ok && (state.reset.IsZero() || now.Before(state.reset)) {
+				status.Remaining = state.remaining
+				status.Requests = state.requests
+				if !state.reset.IsZero() {
+					reset := state.reset
+					status.Reset = &reset
+				}
+			}
+			if paused := pausedTokens[token]; now.Before(paused) {
+				status.PausedUntil = &paused
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// redactToken keeps just enough of token to tell it apart from the others.
+func redactToken(token string) string {
+	if len(token) < 12 {
+		return strings.Repeat("*", len(token))
+	}
+	return token[:4] + "..." + token[len(token)-4:]
+}
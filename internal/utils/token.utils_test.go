@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func resetTokens(t *testing.T) {
+	t.Helper()
+
+	reset := func() {
+		tokensMutex.Lock()
+		clear(tokenStates)
+		clear(pausedTokens)
+		tokensMutex.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestPickTokenPrefersMostRemaining(t *testing.T) {
+	resetTokens(t)
+
+	reset := time.Now().Add(time.Hour)
+	recordQuota("low", githubGraphQL, 100, reset)
+	recordQuota("high", githubGraphQL, 4000, reset)
+	recordQuota("low", githubCore, 5000, reset)
+
+	if token, _ := pickToken([]string{"low", "high"}, githubGraphQL, nil); token != "high" {
+		t.Errorf("graphql token = %q, want high", token)
+	}
+	if token, _ := pickToken([]string{"low", "high"}, githubCore, nil); token != "high" {
+		t.Errorf("core token = %q, want high, which has not reported and counts as full", token)
+	}
+
+	// Once its window resets, a drained token counts as full again.
+	recordQuota("high", githubGraphQL, 0, time.Now().Add(-time.Second))
+	if token, _ := pickToken([]string{"low", "high"}, githubGraphQL, nil); token != "high" {
+		t.Errorf("graphql token after reset = %q, want high", token)
+	}
+}
+
+func TestGitHubDoFailsOverRejectedTokens(t *testing.T) {
+	resetTokens(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer revoked":
+			http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+		case "Bearer limited":
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, `{"message": "You have exceeded a secondary rate limit"}`, http.StatusForbidden)
+		case "Bearer good":
+			w.Header().Set("X-RateLimit-Remaining", "4321")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.Header().Set("X-RateLimit-Resource", "core")
+			_, _ = io.WriteString(w, "ok")
+		default:
+			http.Error(w, "unexpected token", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	newRequest := func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+	}
+
+	// Neither rejected token has reported a quota, so both are tried first.
+	tokens := []string{"revoked", "limited", "good"}
+	recordQuota("good", githubCore, 1, time.Now().Add(time.Hour))
+
+	resp, token, err := githubDo(context.Background(), tokens, githubCore, newRequest)
+	if err != nil {
+		t.Fatalf("githubDo: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || token != "good" {
+		t.Fatalf("githubDo = %d with %q, want 200 with good", resp.StatusCode, token)
+	}
+	if state := tokenStates[tokenQuota{"good", githubCore}]; state == nil || state.remaining != 4321 {
+		t.Errorf("good token state = %+v, want 4321 remaining", state)
+	}
+
+	// The rejected tokens stay paused, so the next request goes straight to
+	// the good one.
+	if token, _ := pickToken(tokens, githubCore, nil); token != "good" {
+		t.Errorf("token after failover = %q, want good", token)
+	}
+
+	// A caller that has given up doesn't move on to the next token.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelAfterFirst := func() (*http.Request, error) {
+		defer cancel()
+		return newRequest()
+	}
+	resetTokens(t)
+	if _, _, err := githubDo(ctx, []string{"revoked", "good"}, githubCore, cancelAfterFirst); !errors.Is(err, context.Canceled) {
+		t.Errorf("githubDo after the caller left = %v, want context.Canceled", err)
+	}
+
+	// With only rejected tokens, the last rejection is returned.
+	resp, _, err = githubDo(context.Background(), []string{"revoked"}, githubCore, newRequest)
+	if err != nil {
+		t.Fatalf("githubDo: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("githubDo with a revoked token = %d, want 401", resp.StatusCode)
+	}
+}
@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"my-realm/internal/models"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var errCircuitOpen = errors.New("circuit open after repeated failures")
+
+// DefaultTransportOptions retries a failed read twice, backing off from
+// 250ms up to 2s, and opens an upstream's circuit for 30s after 5 failures in
+// a row. How long a call may take is up to its upstream's timeout, see
+// UpstreamTimeouts.
+func DefaultTransportOptions() models.TransportOptions {
+	return models.TransportOptions{
+		MaxRetries:       2,
+		BaseBackoff:      250 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// NewHTTPClient returns a client for upstream calls that retries failed reads
+// and stops calling an upstream for a while once it keeps failing, as opts
+// describe. Each client keeps circuits of its own. Fetches are bounded by
+// their upstream's timeout, so the client only cuts off calls that outlast
+// the longest of those, and is meant to be created once they are set.
+func NewHTTPClient(opts models.TransportOptions) *http.Client {
+	return &http.Client{
+		Timeout: longestUpstreamTimeout(),
+		Transport: &resilientTransport{
+			base:     http.DefaultTransport,
+			opts:     opts,
+			circuits: make(map[string]*circuit),
+		},
+	}
+}
+
+// upstreamKey is the context key for the name of the upstream a request is
+// for, so upstreams sharing a host still get a circuit each.
+type upstreamKey struct{}
+
+func withUpstream(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, upstreamKey{}, name)
+}
+
+// requestUpstream is the name of the upstream req is for, falling back to its
+// host for requests made outside a cached fetch.
+func requestUpstream(req *http.Request) string {
+	if name, ok := req.Context().Value(upstreamKey{}).(string); ok && name != "" {
+		return name
+	}
+	return req.URL.Host
+}
+
+// markRead marks req, a POST that only reads such as a GraphQL query, as safe
+// to send again. net/http treats a nil Idempotency-Key the same way, without
+// sending the header.
+func markRead(req *http.Request) {
+	req.Header["Idempotency-Key"] = nil
+}
+
+// resilientTransport retries reads that fail with a transport error or a
+// 502, 503 or 504, backing off exponentially with jitter or for as long as a
+// short Retry-After asks. Around the retries, a circuit per upstream fails
+// calls right away once the upstream has failed too many in a row.
+type resilientTransport struct {
+	base http.RoundTripper
+	opts models.TransportOptions
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.opts.BreakerThreshold <= 0 {
+		return t.send(req)
+	}
+
+	name := requestUpstream(req)
+	c := t.circuit(name)
+	if wait, allowed := c.allow(time.Now()); !allowed {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, &UpstreamError{Upstream: name, Kind: ErrUpstreamUnavailable, RetryAfter: wait, Err: errCircuitOpen}
+	}
+
+	resp, err := t.send(req)
+	switch {
+	case err != nil && errors.Is(err, context.Canceled):
+		// The caller gave up, which says nothing about the upstream.
+		c.abandon()
+	case err != nil || resp.StatusCode >= 500:
+		c.failure(time.Now(), t.opts.BreakerThreshold, t.opts.BreakerCooldown)
+	default:
+		c.success()
+	}
+	return resp, err
+}
+
+func (t *resilientTransport) send(req *http.Request) (*http.Response, error) {
+	retryable := t.opts.MaxRetries > 0 && idempotent(req)
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			var err error
+			if attemptReq, err = rewind(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if !retryable || attempt >= t.opts.MaxRetries {
+			return resp, err
+		}
+		wait, retry := t.retryWait(attempt, resp, err)
+		if !retry || !fitsDeadline(req.Context(), wait) {
+			return resp, err
+		}
+
+		if resp != nil {
+			// Draining lets the connection be reused for the retry.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retryWait reports whether the outcome of attempt is worth retrying, and
+// how long to wait first.
+func (t *resilientTransport) retryWait(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		return t.backoff(attempt), !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+	default:
+		return 0, false
+	}
+
+	wait := retryAfter(resp.Header)
+	switch {
+	case wait > t.opts.MaxBackoff:
+		return 0, false
+	case wait > 0:
+		return wait, true
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// Rate limits without a Retry-After are left to the token rotation
+		// and the caller.
+		return 0, false
+	default:
+		return t.backoff(attempt), true
+	}
+}
+
+// backoff doubles BaseBackoff for each attempt up to MaxBackoff, and waits a
+// random time between half of that and all of it.
+func (t *resilientTransport) backoff(attempt int) time.Duration {
+	wait := min(t.opts.BaseBackoff<<attempt, t.opts.MaxBackoff)
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func (t *resilientTransport) circuit(name string) *circuit {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	c, exists := t.circuits[name]
+	if !exists {
+		c = &circuit{}
+		t.circuits[name] = c
+	}
+	return c
+}
+
+// circuitStates returns the state of every circuit that is not closed.
+func (t *resilientTransport) circuitStates() map[string]string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	states := make(map[string]string)
+	now := time.Now()
+	for name, c := range t.circuits {
+		if state := c.state(now); state != "closed" {
+			states[name] = state
+		}
+	}
+	return states
+}
+
+// idempotent reports whether req can be sent again without side effects.
+func idempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	_, marked := req.Header["Idempotency-Key"]
+	return marked
+}
+
+// rewind copies req with a fresh body for another attempt.
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+	return next, nil
+}
+
+// fitsDeadline reports whether waiting for wait still leaves ctx time.
+func fitsDeadline(ctx context.Context, wait time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > wait
+}
+
+// circuit tracks the recent calls to one upstream. It opens after enough
+// failures in a row and, once its cooldown is over, lets a single trial call
+// through: a success closes it again and a failure reopens it.
+type circuit struct {
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a call may go ahead, and if not, how long until the
+// next trial call.
+func (c *circuit) allow(now time.Time) (time.Duration, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	switch {
+	case c.openUntil.IsZero():
+		return 0, true
+	case now.Before(c.openUntil):
+		return c.openUntil.Sub(now), false
+	case c.probing:
+		return 0, false
+	default:
+		c.probing = true
+		return 0, true
+	}
+}
+
+func (c *circuit) success() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.failures, c.openUntil, c.probing = 0, time.Time{}, false
+}
+
+func (c *circuit) failure(now time.Time, threshold int, cooldown time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.failures++
+	if c.probing || c.failures >= threshold {
+		c.openUntil = now.Add(cooldown)
+	}
+	c.probing = false
+}
+
+// abandon ends a call that says nothing about the upstream, so another call
+// can be the trial.
+func (c *circuit) abandon() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.probing = false
+}
+
+func (c *circuit) state(now time.Time) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	switch {
+	case c.openUntil.IsZero():
+		return "closed"
+	case now.Before(c.openUntil):
+		return "open"
+	default:
+		return "half-open"
+	}
+}
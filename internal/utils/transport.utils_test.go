@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testTransportClient(threshold int, cooldown time.Duration) *http.Client {
+	opts := DefaultTransportOptions()
+	opts.BaseBackoff, opts.MaxBackoff = time.Millisecond, 5*time.Millisecond
+	opts.BreakerThreshold, opts.BreakerCooldown = threshold, cooldown
+	return NewHTTPClient(opts)
+}
+
+func TestTransportRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/patient":
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case calls.Add(1)%3 != 0:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write(body)
+		}
+	}))
+	defer server.Close()
+	client := testTransportClient(0, 0)
+
+	send := func(method, path string, read bool) *http.Response {
+		t.Helper()
+		calls.Store(0)
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewReader([]byte(`{"query":"{ viewer }"}`)))
+		if read {
+			markRead(req)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := send("GET", "/", false); resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("GET = %d after %d calls, want 200 after 3", resp.StatusCode, calls.Load())
+	}
+
+	resp := send("POST", "/graphql", true)
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != `{"query":"{ viewer }"}` {
+		t.Errorf("GraphQL read = %d with body %q, want 200 with the query sent again", resp.StatusCode, body)
+	}
+
+	if resp := send("POST", "/", false); resp.StatusCode != http.StatusBadGateway || calls.Load() != 1 {
+		t.Errorf("unmarked POST = %d after %d calls, want 502 after 1", resp.StatusCode, calls.Load())
+	}
+	if resp := send("GET", "/patient", false); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET with a long Retry-After = %d, want the 503 passed on", resp.StatusCode)
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	var down atomic.Bool
+	var calls atomic.Int64
+	down.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	client := testTransportClient(2, 50*time.Millisecond)
+	transport := client.Transport.(*resilientTransport)
+
+	get := func(upstream string) error {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(withUpstream(context.Background(), upstream), "GET", server.URL, http.NoBody)
+		resp, err := client.Do(req)
+		if err != nil {
+			return requestError(upstream, err)
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	get("flaky")
+	get("flaky")
+	if state := transport.circuitStates()["flaky"]; state != "open" {
+		t.Fatalf("circuit after 2 failures = %q, want open", state)
+	}
+
+	before := calls.Load()
+	err := get("flaky")
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) || !errors.Is(err, ErrUpstreamUnavailable) || upstreamErr.RetryAfter <= 0 {
+		t.Errorf("call through an open circuit = %v, want unavailable with a Retry-After", err)
+	}
+	if calls.Load() != before {
+		t.Error("call through an open circuit reached the upstream")
+	}
+	if err := get("steady"); err != nil || transport.circuitStates()["steady"] != "" {
+		t.Errorf("another upstream on the same host = %v, want its circuit closed", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	down.Store(false)
+	if err := get("flaky"); err != nil {
+		t.Fatalf("trial call after the cooldown: %v", err)
+	}
+	if state, open := transport.circuitStates()["flaky"]; open {
+		t.Errorf("circuit after a successful trial = %q, want closed", state)
+	}
+}
+
+func TestClientTimeoutLeavesRoomForSlowUpstreams(t *testing.T) {
+	useTestCache(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "done")
+	}))
+	defer server.Close()
+
+	// Scaled down, the default timeout is shorter than the upstream takes and
+	// the upstream's own timeout longer.
+	previous := DefaultUpstreamTimeout
+	DefaultUpstreamTimeout = 20 * time.Millisecond
+	UpstreamTimeouts["slowpoke"] = time.Second
+	t.Cleanup(func() {
+		DefaultUpstreamTimeout = previous
+		delete(UpstreamTimeouts, "slowpoke")
+	})
+	client := NewHTTPClient(DefaultTransportOptions())
+
+	body, _, err := cachedFetch(context.Background(), "slowpoke:alice", time.Minute, func(ctx context.Context) (string, error) {
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, http.NoBody)
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", requestError("Slowpoke", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	})
+	if err != nil || body != "done" {
+		t.Errorf("fetch within the upstream's timeout = %q, %v, want it to finish", body, err)
+	}
+}
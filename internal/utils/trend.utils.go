@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"my-realm/internal/history"
+	"my-realm/internal/models"
+	"strings"
+	"time"
+)
+
+// TrendWindows maps the number of days a trend card can cover to how its
+// change over that window is described.
+var TrendWindows = map[int]string{
+	30:  "this month",
+	90:  "this quarter",
+	365: "this year",
+}
+
+var historyMetricLabels = map[string]string{
+	"contributions":   "Contributions",
+	"commits":         "Commits",
+	"pull_requests":   "Pull Requests",
+	"issues":          "Issues",
+	"stars":           "Stars",
+	"followers":       "Followers",
+	"total_solved":    "Problems Solved",
+	"easy_solved":     "Easy Problems Solved",
+	"medium_solved":   "Medium Problems Solved",
+	"hard_solved":     "Hard Problems Solved",
+	"ranking":         "Ranking",
+	"acceptance_rate": "Acceptance Rate",
+	"contest_rating":  "Contest Rating",
+}
+
+const (
+	trendChartWidth  = 450
+	trendChartHeight = 90
+)
+
+// GenerateTrendSVG draws series, covering the last days days, as an area
+// chart with its latest value and how much it changed over the window.
+func GenerateTrendSVG(series models.HistorySeries, days int, color, background string) string {
+	themeColor, bgColor, _ := resolveTheme(color, background)
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="240" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .stat {
+                font: 500 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .value {
+                font: 700 24px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .axis-text {
+                font: 400 11px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.6;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="240"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(25, 35)">
+            <text x="0" y="0" class="title">@%s's %s</text>
+            %s
+        </g>
+    </svg>`
+
+	label := historyMetricLabels[series.Metric]
+	if label == "" {
+		label = series.Metric
+	}
+
+	return fmt.Sprintf(svgTemplate,
+		themeColor,
+		themeColor,
+		themeColor,
+		themeColor,
+		bgColor,
+		themeColor,
+		html.EscapeString(series.Username),
+		html.EscapeString(label),
+		trendBody(series.Points, days, themeColor))
+}
+
+// trendBody renders the value, delta and chart below the card title.
+func trendBody(points []models.HistoryPoint, days int, themeColor string) string {
+	if len(points) == 0 {
+		return `<text x="225" y="110" class="stat" text-anchor="middle">No history yet</text>`
+	}
+
+	latest := points[len(points)-1].Value
+	delta := latest - points[0].Value
+	deltaText := "No change"
+	if delta > 0 {
+		deltaText = "+" + formatMetric(delta)
+	} else if delta < 0 {
+		deltaText = "-" + formatMetric(-delta)
+	}
+
+	end := time.Now().UTC()
+	start := end.AddDate(0, 0, -days)
+
+	return fmt.Sprintf(`
+            <g transform="translate(0, 45)">
+                <text x="0" y="0" class="value">%s</text>
+                <text x="%d" y="0" class="stat" text-anchor="end">%s %s</text>
+            </g>
+            <g transform="translate(0, 70)">
+                %s
+                <text x="0" y="%d" class="axis-text">%s</text>
+                <text x="%d" y="%d" class="axis-text" text-anchor="end">%s</text>
+            </g>`,
+		formatMetric(latest),
+		trendChartWidth, deltaText, TrendWindows[days],
+		trendChart(points, start, end, themeColor),
+		trendChartHeight+20, start.Format("Jan 2, 2006"),
+		trendChartWidth, trendChartHeight+20, end.Format("Jan 2, 2006"))
+}
+
+// trendChart plots points by date between start and end, scaled so the
+// lowest and highest values span the chart.
+func trendChart(points []models.HistoryPoint, start, end time.Time, themeColor string) string {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, point := range points {
+		low = math.Min(low, point.Value)
+		high = math.Max(high, point.Value)
+	}
+
+	span := end.Sub(start).Seconds()
+	coords := make([]string, 0, len(points))
+	var x, y float64
+	for _, point := range points {
+		date, err := time.Parse(history.DateFormat, point.Date)
+		if err != nil {
+			continue
+		}
+		x = math.Max(0, date.Sub(start).Seconds()/span*trendChartWidth)
+		y = trendChartHeight / 2
+		if high > low {
+			y = trendChartHeight - 5 - (point.Value-low)/(high-low)*(trendChartHeight-10)
+		}
+		coords = append(coords, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	if len(coords) == 0 {
+		return ""
+	}
+
+	first := strings.SplitN(coords[0], ",", 2)[0]
+	line := strings.Join(coords, " L ")
+	return fmt.Sprintf(`<path d="M %s,%d L %s L %.1f,%d Z" style="fill: %s; opacity: 0.15;"/>
+                <path d="M %s" style="fill: none; stroke: %s; stroke-width: 2; stroke-linejoin: round;"/>
+                <circle cx="%.1f" cy="%.1f" r="3" style="fill: %s;"/>`,
+		first, trendChartHeight, line, x, trendChartHeight, themeColor,
+		line, themeColor,
+		x, y, themeColor)
+}
+
+// formatMetric writes whole values without decimals and others with one.
+func formatMetric(value float64) string {
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%.1f", value)
+}
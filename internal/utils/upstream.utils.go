@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"my-realm/internal/models"
+	"net/http"
+	"time"
+)
+
+func DefaultUpstreams() models.Upstreams {
+	return models.Upstreams{
+		GitHubAPI:       "https://api.github.com",
+		GitHubGraphQL:   "https://api.github.com/graphql",
+		LeetCodeGraphQL: "https://leetcode.com/graphql",
+		AtCoder:         "https://atcoder.jp",
+		AtCoderProblems: "https://kenkoooo.com/atcoder/atcoder-api/v3",
+		CodeChef:        "https://www.codechef.com",
+		StackExchange:   "https://api.stackexchange.com/2.3",
+		WakaTime:        "https://wakatime.com/api/v1",
+		Exercism:        "https://exercism.org/api/v2",
+		Codewars:        "https://www.codewars.com/api/v1",
+		DevTo:           "https://dev.to/api",
+		Hashnode:        "https://gql.hashnode.com",
+		NpmRegistry:     "https://registry.npmjs.org",
+		NpmDownloads:    "https://api.npmjs.org",
+		PyPI:            "https://pypi.org",
+		PyPIStats:       "https://pypistats.org",
+		CratesIO:        "https://crates.io",
+		GoProxy:         "https://proxy.golang.org",
+	}
+}
+
+var (
+	upstreams  = DefaultUpstreams()
+	httpClient = NewHTTPClient(DefaultTransportOptions())
+)
+
+// DefaultUpstreamTimeout bounds a fetch from any upstream not listed in
+// UpstreamTimeouts, including every call the fetch makes.
+var DefaultUpstreamTimeout = 10 * time.Second
+
+// UpstreamTimeouts bounds fetches from the upstreams that take longer, by the
+// name their cache keys start with, as reported in the rate limit status.
+var UpstreamTimeouts = map[string]time.Duration{
+	// Profiles are read a page of solutions or kata at a time.
+	"exercism": 20 * time.Second,
+	"codewars": 20 * time.Second,
+	// A profile takes several API calls, and WakaTime can be slow to total
+	// long ranges.
+	"stackexchange": 15 * time.Second,
+	"wakatime":      15 * time.Second,
+}
+
+// upstreamTimeout is how long a fetch for key may take.
+func upstreamTimeout(key string) time.Duration {
+	if timeout, exists := UpstreamTimeouts[upstreamName(key)]; exists {
+		return timeout
+	}
+	return DefaultUpstreamTimeout
+}
+
+// longestUpstreamTimeout is the longest a fetch from any upstream may take.
+func longestUpstreamTimeout() time.Duration {
+	longest := DefaultUpstreamTimeout
+	for _, timeout := range UpstreamTimeouts {
+		longest = max(longest, timeout)
+	}
+	return longest
+}
+
+// SetUpstreams replaces the upstream base URLs. It is meant to be called
+// before the server starts handling requests.
+func SetUpstreams(u models.Upstreams) {
+	upstreams = u
+}
+
+// SetHTTPClient replaces the client every provider uses for upstream calls.
+// It is meant to be called before the server starts handling requests.
+func SetHTTPClient(client *http.Client) {
+	httpClient = client
+}
+
+// httpGet sends a GET for target that is cancelled along with ctx.
+func httpGet(ctx context.Context, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	return httpClient.Do(req)
+}
@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"my-realm/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// wakaTimeCardItems is how many languages, editors and projects the card shows.
+const wakaTimeCardItems = 5
+
+var ErrWakaTimeKeyMissing = &MissingSettingError{Setting: "WAKATIME_API_KEY", Feature: "Your own WakaTime stats"}
+
+// WakaTimeRanges maps the supported stats ranges to their card labels.
+var WakaTimeRanges = map[string]string{
+	"last_7_days":   "Last 7 Days",
+	"last_30_days":  "Last 30 Days",
+	"last_6_months": "Last 6 Months",
+	"last_year":     "Last Year",
+	"all_time":      "All Time",
+}
+
+// FetchWakaTimeStats returns coding activity for username over statsRange.
+// username "current" refers to the owner of apiKey; other users must have
+// made their stats public.
+func FetchWakaTimeStats(ctx context.Context, username, statsRange, apiKey string) (*models.WakaTimeStats, models.CacheInfo, error) {
+	if username == "current" && apiKey == "" {
+		return nil, models.CacheInfo{}, ErrWakaTimeKeyMissing
+	}
+
+	return cachedFetch(ctx, "wakatime:"+username+":"+statsRange, CacheTTLs["wakatime"], func(ctx context.Context) (*models.WakaTimeStats, error) {
+		return fetchWakaTimeStats(ctx, username, statsRange, apiKey)
+	})
+}
+
+func fetchWakaTimeStats(ctx context.Context, username, statsRange, apiKey string) (*models.WakaTimeStats, error) {
+	userPath := "/users/" + url.PathEscape(username)
+
+	var statsResp models.WakaTimeStatsResponse
+	if err := wakaTimeGet(ctx, userPath+"/stats/"+statsRange, apiKey, &statsResp); err != nil {
+		return nil, err
+	}
+
+	var allTimeResp models.WakaTimeAllTimeResponse
+	if err := wakaTimeGet(ctx, userPath+"/all_time_since_today", apiKey, &allTimeResp); err != nil {
+		return nil, err
+	}
+
+	stats := &models.WakaTimeStats{
+		Range:          statsRange,
+		TotalSeconds:   statsResp.Data.TotalSeconds,
+		AllTimeSeconds: allTimeResp.Data.TotalSeconds,
+		Languages:      toWakaTimeItems(statsResp.Data.Languages),
+		Editors:        toWakaTimeItems(statsResp.Data.Editors),
+		Projects:       toWakaTimeItems(statsResp.Data.Projects),
+	}
+
+	return stats, nil
+}
+
+func wakaTimeGet(ctx context.Context, path, apiKey string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", upstreams.WakaTime+path, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(apiKey)))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return requestError("WakaTime", err)
+	}
+	defer resp.Body.Close()
+
+	// WakaTime answers 202 while it is still computing a range for the first time.
+	if resp.StatusCode == http.StatusAccepted {
+		return fmt.Errorf("wakatime is still calculating %s", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return statusError("WakaTime", resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+func toWakaTimeItems(items []models.WakaTimeStatsItem) []models.WakaTimeItem {
+	result := make([]models.WakaTimeItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, models.WakaTimeItem{
+			Name:         item.Name,
+			TotalSeconds: item.TotalSeconds,
+			Percent:      item.Percent,
+		})
+	}
+	return result
+}
+
+func formatHours(seconds float64) string {
+	return fmt.Sprintf("%.1f hrs", seconds/3600)
+}
+
+func GenerateWakaTimeStatsSVG(stats *models.WakaTimeStats, username, color, background string) string {
+	themeColor, bgColor, barBgColor := resolveTheme(color, background)
+
+	title := "Coding Time"
+	if username != "current" {
+		title = fmt.Sprintf("@%s's Coding Time", username)
+	}
+
+	svgTemplate := `<?xml version="1.0" encoding="UTF-8"?>
+    <svg width="500" height="%d" xmlns="http://www.w3.org/2000/svg">
+        <style>
+            .title {
+                font: 600 18px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+            }
+            .section-title {
+                font: 600 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.8;
+            }
+            .lang-text {
+                font: 400 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .percentage-text {
+                font: 500 14px 'Inter', 'Segoe UI', Ubuntu, Sans-Serif;
+                fill: %s;
+                opacity: 0.9;
+            }
+            .bar-bg {
+                fill: %s;
+            }
+            .bar {
+                fill: %s;
+                opacity: 0.8;
+            }
+        </style>
+
+        <rect
+            x="0"
+            y="0"
+            width="500"
+            height="%d"
+            fill="%s"
+            rx="12"
+            ry="12"
+            stroke="%s"
+            stroke-width="3"
+            stroke-opacity="0.7"
+        />
+
+        <g transform="translate(30, 35)">
+            <text x="0" y="0" class="title">%s</text>
+            <g transform="translate(0, 30)">
+                <text x="0" y="0" class="lang-text">%s</text>
+                <text x="440" y="0" class="percentage-text" text-anchor="end">%s</text>
+                <text x="0" y="25" class="lang-text">All Time</text>
+                <text x="440" y="25" class="percentage-text" text-anchor="end">%s</text>
+            </g>
+            <g transform="translate(0, 95)">
+                %s
+            </g>
+        </g>
+    </svg>`
+
+	var sections strings.Builder
+	offset := 0
+	for _, section := range []struct {
+		Name  string
+		Items []models.WakaTimeItem
+	}{
+		{"Languages", stats.Languages},
+		{"Editors", stats.Editors},
+		{"Projects", stats.Projects},
+	} {
+		if len(section.Items) == 0 {
+			continue
+		}
+
+		items := section.Items
+		if len(items) > wakaTimeCardItems {
+			items = items[:wakaTimeCardItems]
+		}
+
+		sections.WriteString(fmt.Sprintf(`
+            <text x="0" y="%d" class="section-title">%s</text>`, offset, section.Name))
+		offset += 30
+		for _, item := range items {
+			sections.WriteString(horizontalBar(offset, item.Name, formatHours(item.TotalSeconds), item.Percent))
+			offset += 40
+		}
+		offset += 10
+	}
+
+	height := 120 + offset
+
+	rangeLabel := WakaTimeRanges[stats.Range]
+	if rangeLabel == "" {
+		rangeLabel = stats.Range
+	}
+
+	return fmt.Sprintf(svgTemplate,
+		height,
+		themeColor,
+		themeColor,
+		themeColor,
+		themeColor,
+		barBgColor,
+		themeColor,
+		height,
+		bgColor,
+		themeColor,
+		html.EscapeString(title),
+		rangeLabel,
+		formatHours(stats.TotalSeconds),
+		formatHours(stats.AllTimeSeconds),
+		sections.String())
+}
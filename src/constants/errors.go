@@ -6,6 +6,11 @@ type Error struct {
 	Status        int    `json:"status"`
 }
 
+// Error lets an Error be passed around as an error until it is sent.
+func (e Error) Error() string {
+	return e.PrettyMessage
+}
+
 var (
 	ErrorTokenInvalid = Error{
 		Message:       "Token Invalid",
@@ -47,6 +52,11 @@ var (
 		PrettyMessage: "The request could not be completed due to a conflict with the current state of the resource.",
 		Status:        409,
 	}
+	ErrorTooManyRequests = Error{
+		Message:       "Too Many Requests",
+		PrettyMessage: "The user has sent too many requests in a given amount of time.",
+		Status:        429,
+	}
 	ErrorInternalServerError = Error{
 		Message:       "Internal Server Error",
 		PrettyMessage: "The server encountered an unexpected condition which prevented it from fulfilling the request.",
@@ -57,4 +67,9 @@ var (
 		PrettyMessage: "The server is currently unable to handle the request due to a temporary overloading or maintenance of the server.",
 		Status:        503,
 	}
+	ErrorGatewayTimeout = Error{
+		Message:       "Gateway Timeout",
+		PrettyMessage: "The server did not receive a timely response from an upstream server it needed to complete the request.",
+		Status:        504,
+	}
 )
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetAtCoderStats(c *fiber.Ctx) error {
+	username := c.Query("username")
+	if username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorMissingFields)
+	}
+	if !validUsername("atcoder", username) {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
+
+	stats, info, err := utils.FetchAtCoderStats(c.UserContext(), username)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved AtCoder statistics",
+		Status:        200,
+		Data:          stats,
+	}
+
+	setCacheStatus(c, info)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func GetAtCoderStatsAsSVG(c *fiber.Ctx) error {
+	username := c.Query("username")
+	if username == "" {
+		return sendErrorCard(c, constants.ErrorMissingFields)
+	}
+	if !validUsername("atcoder", username) {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+
+	stats, info, err := utils.FetchAtCoderStats(c.UserContext(), username)
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	svg := utils.GenerateAtCoderStatsSVG(stats, username, color, background)
+
+	return sendCard(c, svg, info)
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"my-realm/internal/models"
+	"my-realm/internal/utils"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// setCacheStatus marks responses built from stale cached data.
+func setCacheStatus(c *fiber.Ctx, info models.CacheInfo) {
+	if info.Stale {
+		c.Set("X-Cache", "STALE")
+	}
+}
+
+// sendCard sends a rendered SVG card, with a footnote saying when its data
+// was fetched if the last_updated query parameter is set.
+func sendCard(c *fiber.Ctx, svg string, info models.CacheInfo) error {
+	setCacheStatus(c, info)
+	if c.QueryBool("last_updated") && !info.FetchedAt.IsZero() {
+		svg = utils.AddUpdatedFootnote(svg, info.FetchedAt, c.Query("color", "red"))
+	}
+
+	if !info.FetchedAt.IsZero() {
+		c.Set("Last-Modified", info.FetchedAt.UTC().Format(http.TimeFormat))
+	}
+
+	c.Set("Content-Type", "image/svg+xml")
+	return c.SendString(svg)
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetCodeChefStats(c *fiber.Ctx) error {
+	username := c.Query("username")
+	if username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorMissingFields)
+	}
+	if !validUsername("codechef", username) {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
+
+	stats, info, err := utils.FetchCodeChefStats(c.UserContext(), username)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved CodeChef statistics",
+		Status:        200,
+		Data:          stats,
+	}
+
+	setCacheStatus(c, info)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func GetCodeChefStatsAsSVG(c *fiber.Ctx) error {
+	username := c.Query("username")
+	if username == "" {
+		return sendErrorCard(c, constants.ErrorMissingFields)
+	}
+	if !validUsername("codechef", username) {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+
+	stats, info, err := utils.FetchCodeChefStats(c.UserContext(), username)
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	svg := utils.GenerateCodeChefStatsSVG(stats, username, color, background)
+
+	return sendCard(c, svg, info)
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// errorResponses maps the errors the utils return onto the response that
+// reports them. Anything else is a 500.
+var errorResponses = []struct {
+	err      error
+	response constants.Error
+}{
+	{utils.ErrNotFound, constants.ErrorNotFound},
+	{utils.ErrRateLimited, constants.ErrorTooManyRequests},
+	{utils.ErrUpstreamTimeout, constants.ErrorGatewayTimeout},
+	{utils.ErrUpstreamUnavailable, constants.ErrorServiceUnavailable},
+	{utils.ErrStackExchangeBackoff, constants.ErrorServiceUnavailable},
+	{utils.ErrHistoryDisabled, constants.ErrorServiceUnavailable},
+	{utils.ErrFeedHostNotAllowed, constants.ErrorForbidden},
+	{utils.ErrUnknownRegistry, constants.ErrorBadRequest},
+	{utils.ErrUnknownMetric, constants.ErrorBadRequest},
+}
+
+// errorResponse finds the response reporting err. Upstream errors and
+// missing settings carry a message naming the upstream or setting, which
+// replaces the generic one.
+func errorResponse(err error) constants.Error {
+	var response constants.Error
+	if errors.As(err, &response) {
+		return response
+	}
+
+	response = constants.ErrorInternalServerError
+	for _, mapping := range errorResponses {
+		if errors.Is(err, mapping.err) {
+			response = mapping.response
+			break
+		}
+	}
+
+	var upstreamErr *utils.UpstreamError
+	if errors.As(err, &upstreamErr) {
+		response.PrettyMessage = upstreamErr.Message()
+	}
+	var missingErr *utils.MissingSettingError
+	if errors.As(err, &missingErr) {
+		response = constants.ErrorServiceUnavailable
+		response.PrettyMessage = missingErr.Message()
+	}
+	return response
+}
+
+// setRetryAfter passes on how long an upstream asked us to wait.
+func setRetryAfter(c *fiber.Ctx, err error) {
+	var upstreamErr *utils.UpstreamError
+	if errors.As(err, &upstreamErr) && upstreamErr.RetryAfter > 0 {
+		c.Set("Retry-After", strconv.Itoa(int(math.Ceil(upstreamErr.RetryAfter.Seconds()))))
+	}
+}
+
+// sendError reports err as JSON with the status it maps to.
+func sendError(c *fiber.Ctx, err error) error {
+	response := errorResponse(err)
+	setRetryAfter(c, err)
+	return c.Status(response.Status).JSON(response)
+}
+
+// sendErrorCard reports err as a card in the requested theme. It is sent with
+// a 200 so that image proxies such as GitHub's still show it, and with the
+// status it maps to in X-Error-Status. Error cards are never cached.
+func sendErrorCard(c *fiber.Ctx, err error) error {
+	response := errorResponse(err)
+	setRetryAfter(c, err)
+
+	svg := utils.GenerateErrorSVG(response.Message, response.PrettyMessage,
+		c.Query("color", "red"), c.Query("background", "black"))
+
+	c.Set("X-Error-Status", strconv.Itoa(response.Status))
+	c.Set("Cache-Control", "no-store")
+	c.Set("Content-Type", "image/svg+xml")
+	return c.Status(fiber.StatusOK).SendString(svg)
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"my-realm/internal/config"
+	"my-realm/internal/models"
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetFeed(c *fiber.Ctx) error {
+	feed, info, err := fetchRequestedFeed(c)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved latest posts",
+		Status:        200,
+		Data:          feed,
+	}
+
+	setCacheStatus(c, info)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func GetFeedAsSVG(c *fiber.Ctx) error {
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+	limit := 5
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			return sendErrorCard(c, constants.ErrorBadRequest)
+		}
+		limit = min(limit, utils.FeedMaxPosts)
+	}
+
+	feed, info, err := fetchRequestedFeed(c)
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	svg := utils.GenerateFeedSVG(feed, limit, color, background)
+
+	return sendCard(c, svg, info)
+}
+
+// fetchRequestedFeed picks the source from the url, devto or hashnode query
+// parameter, in that order.
+func fetchRequestedFeed(c *fiber.Ctx) (*models.Feed, models.CacheInfo, error) {
+	if feedURL := c.Query("url"); feedURL != "" {
+		env := config.Current()
+		var allowedHosts []string
+		if env.FeedAllowedHosts != "" {
+			allowedHosts = strings.Split(env.FeedAllowedHosts, ",")
+		}
+		return utils.FetchFeed(c.UserContext(), feedURL, allowedHosts)
+	}
+	if username := c.Query("devto"); username != "" {
+		if !validUsername("devto", username) {
+			return nil, models.CacheInfo{}, constants.ErrorBadRequest
+		}
+		return utils.FetchDevToFeed(c.UserContext(), username)
+	}
+	if username := c.Query("hashnode"); username != "" {
+		if !validUsername("hashnode", username) {
+			return nil, models.CacheInfo{}, constants.ErrorBadRequest
+		}
+		return utils.FetchHashnodeFeed(c.UserContext(), username)
+	}
+	return nil, models.CacheInfo{}, constants.ErrorMissingFields
+}
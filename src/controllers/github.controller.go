@@ -1,45 +1,29 @@
 package controllers
 
 import (
-	"encoding/json"
-	"fmt"
 	"my-realm/internal/config"
+	"my-realm/internal/models"
 	"my-realm/internal/utils"
 	"my-realm/src/constants"
-	"net/http"
-	"sync"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-type Repository struct {
-	Language string `json:"language"`
-}
-
 func GetMostUsedLanguages(c *fiber.Ctx) error {
+	instance, ok := githubInstance(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
 	username := c.Query("username", "risv1")
-	githubAPIURL := fmt.Sprintf("https://api.github.com/users/%s/repos", username)
-	resp, err := http.Get(githubAPIURL)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(constants.ErrorInternalServerError)
+	if !validUsername(githubPlatform(instance), username) {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
 	}
-	defer resp.Body.Close()
-
-	var repos []Repository
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(constants.ErrorInternalServerError)
+	repos, info, err := utils.FetchUserRepos(c.UserContext(), instance, username)
+	if err != nil {
+		return sendError(c, err)
 	}
 
-	languageCount := make(map[string]int)
-	totalRepos := 0
-
-	for _, repo := range repos {
-		if repo.Language != "" {
-			languageCount[repo.Language]++
-			totalRepos++
-		}
-	}
+	languageCount, totalRepos := utils.CountLanguages(repos)
 
 	languagePercentages := make(map[string]float64)
 	for lang, count := range languageCount {
@@ -54,24 +38,23 @@ func GetMostUsedLanguages(c *fiber.Ctx) error {
 		Data:          languagePercentages,
 	}
 
+	setCacheStatus(c, info)
 	return c.Status(fiber.StatusOK).JSON(response)
 }
 
-var (
-	svgCache    string
-	cacheTTL    = 1 * time.Second
-	lastUpdated time.Time
-	cacheMutex  sync.RWMutex
-)
-
 func GetProfileStats(c *fiber.Ctx) error {
-	env := config.LoadEnv()
+	instance, ok := githubInstance(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
 	username := c.Query("username", "risv1")
-	token := env.GithubToken
+	if !validUsername(githubPlatform(instance), username) {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
 
-	stats, err := utils.FetchGitHubStats(username, token)
+	stats, info, err := utils.FetchGitHubStats(c.UserContext(), instance, username)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(constants.ErrorInternalServerError)
+		return sendError(c, err)
 	}
 
 	response := constants.Response{
@@ -81,69 +64,67 @@ func GetProfileStats(c *fiber.Ctx) error {
 		Data:          stats,
 	}
 
+	setCacheStatus(c, info)
 	return c.Status(fiber.StatusOK).JSON(response)
 }
 
 func GetLanguagesAsSVG(c *fiber.Ctx) error {
+	instance, ok := githubInstance(c)
+	if !ok {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
 	username := c.Query("username", "risv1")
+	if !validUsername(githubPlatform(instance), username) {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
 	color := c.Query("color", "red")
 	background := c.Query("background", "black")
 
-	githubAPIURL := fmt.Sprintf("https://api.github.com/users/%s/repos", username)
-	resp, err := http.Get(githubAPIURL)
+	repos, info, err := utils.FetchUserRepos(c.UserContext(), instance, username)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(constants.ErrorInternalServerError)
-	}
-	defer resp.Body.Close()
-
-	var repos []Repository
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(constants.ErrorInternalServerError)
+		return sendErrorCard(c, err)
 	}
 
-	languageCount := make(map[string]int)
-	totalRepos := 0
-
-	for _, repo := range repos {
-		if repo.Language != "" {
-			languageCount[repo.Language]++
-			totalRepos++
-		}
-	}
+	languageCount, totalRepos := utils.CountLanguages(repos)
 
 	svg := utils.GenerateLanguagesSVG(languageCount, totalRepos, username, color, background)
 
-	c.Set("Content-Type", "image/svg+xml")
-	return c.SendString(svg)
+	return sendCard(c, svg, info)
 }
 
 func GetStatsAsSVG(c *fiber.Ctx) error {
-	cacheMutex.RLock()
-	if time.Since(lastUpdated) < cacheTTL && svgCache != "" {
-		defer cacheMutex.RUnlock()
-		c.Set("Content-Type", "image/svg+xml")
-		return c.SendString(svgCache)
+	instance, ok := githubInstance(c)
+	if !ok {
+		return sendErrorCard(c, constants.ErrorBadRequest)
 	}
-	cacheMutex.RUnlock()
-
-	env := config.LoadEnv()
 	username := c.Query("username", "risv1")
-	token := env.GithubToken
+	if !validUsername(githubPlatform(instance), username) {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
 	color := c.Query("color", "red")
 	background := c.Query("background", "black")
 
-	stats, err := utils.FetchGitHubStats(username, token)
+	stats, info, err := utils.FetchGitHubStats(c.UserContext(), instance, username)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(constants.ErrorInternalServerError)
+		return sendErrorCard(c, err)
 	}
 
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
-
 	svg := utils.GenerateStatsSVG(stats, username, color, background)
-	svgCache = svg
-	lastUpdated = time.Now()
 
-	c.Set("Content-Type", "image/svg+xml")
-	return c.SendString(svg)
+	return sendCard(c, svg, info)
+}
+
+// githubPlatform names the username rules of instance: github.com's, or the
+// looser ones of a GitHub Enterprise Server.
+func githubPlatform(instance models.GitHubInstance) string {
+	if instance.APIURL != "" {
+		return "github-enterprise"
+	}
+	return "github"
+}
+
+// githubInstance looks up the GitHub host named by the instance query
+// parameter, defaulting to the one configured by GITHUB_TOKEN.
+func githubInstance(c *fiber.Ctx) (models.GitHubInstance, bool) {
+	return config.Current().GitHubInstance(c.Query("instance"))
 }
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetHistory(c *fiber.Ctx) error {
+	provider := c.Query("provider")
+	username := c.Query("username")
+	metric := c.Query("metric")
+	if provider == "" || username == "" || metric == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorMissingFields)
+	}
+	if !validUsername(provider, username) {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
+
+	series, err := utils.LoadHistory(provider, username, metric, time.Time{})
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved history",
+		Status:        200,
+		Data:          series,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
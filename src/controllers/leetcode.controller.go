@@ -14,10 +14,13 @@ func GetLeetCodeStats(c *fiber.Ctx) error {
 			"error": "Username is required",
 		})
 	}
+	if !validUsername("leetcode", username) {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
 
-	stats, err := utils.FetchLeetCodeStats(username)
+	stats, info, err := utils.FetchLeetCodeStats(c.UserContext(), username)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(constants.ErrorInternalServerError)
+		return sendError(c, err)
 	}
 
 	response := constants.Response{
@@ -27,21 +30,27 @@ func GetLeetCodeStats(c *fiber.Ctx) error {
 		Data:          stats,
 	}
 
+	setCacheStatus(c, info)
 	return c.Status(fiber.StatusOK).JSON(response)
 }
 
 func GetLeetCodeStatsAsSVG(c *fiber.Ctx) error {
 	username := c.Query("username")
+	if username == "" {
+		return sendErrorCard(c, constants.ErrorMissingFields)
+	}
+	if !validUsername("leetcode", username) {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
 	color := c.Query("color", "red")
 	background := c.Query("background", "black")
 
-	stats, err := utils.FetchLeetCodeStats(username)
+	stats, info, err := utils.FetchLeetCodeStats(c.UserContext(), username)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(constants.ErrorInternalServerError)
+		return sendErrorCard(c, err)
 	}
 
 	svg := utils.GenerateLeetCodeStatsSVG(stats, username, color, background)
 
-	c.Set("Content-Type", "image/svg+xml")
-	return c.SendString(svg)
+	return sendCard(c, svg, info)
 }
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// packageNamePattern covers npm (including scopes), PyPI, crates.io and Go
+// module paths.
+var packageNamePattern = regexp.MustCompile(`^[@A-Za-z0-9._~/-]+$`)
+
+func GetPackageStats(c *fiber.Ctx) error {
+	registry := c.Query("registry", "npm")
+	name := c.Query("name")
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorMissingFields)
+	}
+	if !packageNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
+
+	stats, info, err := utils.FetchPackageStats(c.UserContext(), registry, name)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved package statistics",
+		Status:        200,
+		Data:          stats,
+	}
+
+	setCacheStatus(c, info)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func GetPackageStatsAsSVG(c *fiber.Ctx) error {
+	registry := c.Query("registry", "npm")
+	name := c.Query("name")
+	style := c.Query("style", "card")
+	metric := c.Query("metric", "version")
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+	if name == "" {
+		return sendErrorCard(c, constants.ErrorMissingFields)
+	}
+	if !packageNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
+
+	stats, info, err := utils.FetchPackageStats(c.UserContext(), registry, name)
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	var svg string
+	if style == "badge" {
+		svg = utils.GeneratePackageBadgeSVG(stats, metric, color)
+	} else {
+		svg = utils.GeneratePackageSVG(stats, color, background)
+	}
+
+	return sendCard(c, svg, info)
+}
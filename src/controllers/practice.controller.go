@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"my-realm/internal/models"
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type practiceFetcher func(ctx context.Context, username string) (*models.PracticeStats, models.CacheInfo, error)
+
+func GetExercismStats(c *fiber.Ctx) error {
+	return getPracticeStats(c, "exercism", utils.FetchExercismStats, "Successfully retrieved Exercism statistics")
+}
+
+func GetExercismStatsAsSVG(c *fiber.Ctx) error {
+	return getPracticeStatsAsSVG(c, "exercism", utils.FetchExercismStats)
+}
+
+func GetCodewarsStats(c *fiber.Ctx) error {
+	return getPracticeStats(c, "codewars", utils.FetchCodewarsStats, "Successfully retrieved Codewars statistics")
+}
+
+func GetCodewarsStatsAsSVG(c *fiber.Ctx) error {
+	return getPracticeStatsAsSVG(c, "codewars", utils.FetchCodewarsStats)
+}
+
+// GetCombinedPracticeStats returns every platform named in the query, e.g.
+// ?exercism=alice&codewars=alice42.
+func GetCombinedPracticeStats(c *fiber.Ctx) error {
+	profiles, info, err := fetchCombinedPractice(c)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved practice platform statistics",
+		Status:        200,
+		Data:          profiles,
+	}
+
+	setCacheStatus(c, info)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func GetCombinedPracticeStatsAsSVG(c *fiber.Ctx) error {
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+
+	profiles, info, err := fetchCombinedPractice(c)
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	svg := utils.GeneratePracticeSVG(profiles, color, background)
+
+	return sendCard(c, svg, info)
+}
+
+func getPracticeStats(c *fiber.Ctx, platform string, fetch practiceFetcher, message string) error {
+	username := c.Query("username")
+	if username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorMissingFields)
+	}
+	if !validUsername(platform, username) {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
+
+	stats, info, err := fetch(c.UserContext(), username)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: message,
+		Status:        200,
+		Data:          stats,
+	}
+
+	setCacheStatus(c, info)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func getPracticeStatsAsSVG(c *fiber.Ctx, platform string, fetch practiceFetcher) error {
+	username := c.Query("username")
+	if username == "" {
+		return sendErrorCard(c, constants.ErrorMissingFields)
+	}
+	if !validUsername(platform, username) {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+
+	stats, info, err := fetch(c.UserContext(), username)
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	svg := utils.GeneratePracticeSVG([]*models.PracticeStats{stats}, color, background)
+
+	return sendCard(c, svg, info)
+}
+
+// fetchCombinedPractice fetches each requested platform. The returned
+// CacheInfo is stale if any profile is, and dated by the oldest one.
+func fetchCombinedPractice(c *fiber.Ctx) ([]*models.PracticeStats, models.CacheInfo, error) {
+	fetchers := []struct {
+		Param string
+		Fetch practiceFetcher
+	}{
+		{"exercism", utils.FetchExercismStats},
+		{"codewars", utils.FetchCodewarsStats},
+	}
+
+	var profiles []*models.PracticeStats
+	var combined models.CacheInfo
+	for _, fetcher := range fetchers {
+		username := c.Query(fetcher.Param)
+		if username == "" {
+			continue
+		}
+		if !validUsername(fetcher.Param, username) {
+			return nil, combined, constants.ErrorBadRequest
+		}
+
+		stats, info, err := fetcher.Fetch(c.UserContext(), username)
+		if err != nil {
+			return nil, combined, err
+		}
+		profiles = append(profiles, stats)
+
+		combined.Stale = combined.Stale || info.Stale
+		if combined.FetchedAt.IsZero() || info.FetchedAt.Before(combined.FetchedAt) {
+			combined.FetchedAt = info.FetchedAt
+		}
+	}
+
+	if len(profiles) == 0 {
+		return nil, combined, constants.ErrorMissingFields
+	}
+	return profiles, combined, nil
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"my-realm/internal/config"
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetStackExchangeStats(c *fiber.Ctx) error {
+	env := config.Current()
+	userID := c.Query("id")
+	site := c.Query("site", "stackoverflow")
+	if userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorMissingFields)
+	}
+	if _, err := strconv.ParseUint(userID, 10, 64); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
+
+	stats, info, err := utils.FetchStackExchangeStats(c.UserContext(), userID, site, env.StackExchangeKey)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved Stack Exchange statistics",
+		Status:        200,
+		Data:          stats,
+	}
+
+	setCacheStatus(c, info)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func GetStackExchangeStatsAsSVG(c *fiber.Ctx) error {
+	env := config.Current()
+	userID := c.Query("id")
+	site := c.Query("site", "stackoverflow")
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+	if userID == "" {
+		return sendErrorCard(c, constants.ErrorMissingFields)
+	}
+	if _, err := strconv.ParseUint(userID, 10, 64); err != nil {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
+
+	stats, info, err := utils.FetchStackExchangeStats(c.UserContext(), userID, site, env.StackExchangeKey)
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	svg := utils.GenerateStackExchangeStatsSVG(stats, color, background)
+
+	return sendCard(c, svg, info)
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"my-realm/internal/config"
+	"my-realm/internal/models"
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetRateLimitStatus(c *fiber.Ctx) error {
+	status := models.RateLimitStatus{
+		GitHub:    []models.TokenStatus{},
+		Upstreams: utils.UpstreamStatuses(),
+		Cards:     utils.CardCacheStats(),
+	}
+	for _, instance := range config.Current().GitHubInstances() {
+		status.GitHub = append(status.GitHub, utils.GitHubTokenStatuses(instance.Name, instance.Tokens)...)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved rate limit status",
+		Status:        200,
+		Data:          status,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
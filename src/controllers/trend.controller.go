@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"my-realm/internal/models"
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetTrendAsSVG(c *fiber.Ctx) error {
+	provider := c.Query("provider")
+	username := c.Query("username")
+	metric := c.Query("metric")
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+	if provider == "" || username == "" || metric == "" {
+		return sendErrorCard(c, constants.ErrorMissingFields)
+	}
+	if !validUsername(provider, username) {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
+
+	days := c.QueryInt("days", 30)
+	if _, ok := utils.TrendWindows[days]; !ok {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
+
+	series, err := utils.LoadHistory(provider, username, metric, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	svg := utils.GenerateTrendSVG(series, days, color, background)
+
+	return sendCard(c, svg, models.CacheInfo{})
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import "regexp"
+
+// usernamePatterns holds the username rules of each platform, so malformed
+// names are rejected before they reach an upstream or a card.
+var usernamePatterns = map[string]*regexp.Regexp{
+	// GitHub logins are up to 39 characters, alphanumeric with hyphens after
+	// the first. New logins can't repeat or end with a hyphen, but some older
+	// accounts do, so hyphens are allowed anywhere past the first character.
+	"github": regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,38}$`),
+	// GitHub Enterprise Server derives logins from its identity provider,
+	// which can add underscores and dots, so its hosts get a looser check.
+	"github-enterprise": regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,254}$`),
+	"leetcode":          regexp.MustCompile(`^[A-Za-z0-9_-]{1,30}$`),
+	"atcoder":           regexp.MustCompile(`^[A-Za-z0-9_]{3,16}$`),
+	"codechef":          regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,29}$`),
+	"wakatime":          regexp.MustCompile(`^[A-Za-z0-9_.-]{1,50}$`),
+	"exercism":          regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`),
+	"codewars":          regexp.MustCompile(`^[A-Za-z0-9_.-]{1,50}$`),
+	"devto":             regexp.MustCompile(`^[A-Za-z0-9_]{1,30}$`),
+	"hashnode":          regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`),
+}
+
+const githubLoginMaxLength = 39
+
+// validUsername reports whether username follows the rules of platform.
+func validUsername(platform, username string) bool {
+	if platform == "github" && len(username) > githubLoginMaxLength {
+		return false
+	}
+	pattern, exists := usernamePatterns[platform]
+	return exists && pattern.MatchString(username)
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"my-realm/internal/config"
+	"my-realm/internal/utils"
+	"my-realm/src/constants"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetWakaTimeStats(c *fiber.Ctx) error {
+	env := config.Current()
+	username := c.Query("username", "current")
+	statsRange := c.Query("range", "last_7_days")
+	if _, ok := utils.WakaTimeRanges[statsRange]; !ok || !validUsername("wakatime", username) {
+		return c.Status(fiber.StatusBadRequest).JSON(constants.ErrorBadRequest)
+	}
+
+	stats, info, err := utils.FetchWakaTimeStats(c.UserContext(), username, statsRange, env.WakaTimeAPIKey)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	response := constants.Response{
+		Message:       "OK",
+		PrettyMessage: "Successfully retrieved WakaTime statistics",
+		Status:        200,
+		Data:          stats,
+	}
+
+	setCacheStatus(c, info)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func GetWakaTimeStatsAsSVG(c *fiber.Ctx) error {
+	env := config.Current()
+	username := c.Query("username", "current")
+	statsRange := c.Query("range", "last_7_days")
+	color := c.Query("color", "red")
+	background := c.Query("background", "black")
+	if _, ok := utils.WakaTimeRanges[statsRange]; !ok || !validUsername("wakatime", username) {
+		return sendErrorCard(c, constants.ErrorBadRequest)
+	}
+
+	stats, info, err := utils.FetchWakaTimeStats(c.UserContext(), username, statsRange, env.WakaTimeAPIKey)
+	if err != nil {
+		return sendErrorCard(c, err)
+	}
+
+	svg := utils.GenerateWakaTimeStatsSVG(stats, username, color, background)
+
+	return sendCard(c, svg, info)
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"my-realm/internal/config"
+	"my-realm/internal/models"
+	"my-realm/internal/utils"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultCardCachePolicy follows the 10 minute cache most providers keep.
+var defaultCardCachePolicy = models.CachePolicy{
+	MaxAge:               600,
+	SMaxAge:              600,
+	StaleWhileRevalidate: 86400,
+}
+
+// cardCachePolicies holds the endpoints whose providers cache for longer.
+var cardCachePolicies = map[string]models.CachePolicy{
+	"/api/stackexchange/svg": {MaxAge: 1800, SMaxAge: 1800, StaleWhileRevalidate: 86400},
+	"/api/wakatime/svg":      {MaxAge: 1800, SMaxAge: 1800, StaleWhileRevalidate: 86400},
+	"/api/exercism/svg":      {MaxAge: 1800, SMaxAge: 1800, StaleWhileRevalidate: 86400},
+	"/api/codewars/svg":      {MaxAge: 1800, SMaxAge: 1800, StaleWhileRevalidate: 86400},
+	"/api/practice/svg":      {MaxAge: 1800, SMaxAge: 1800, StaleWhileRevalidate: 86400},
+	"/api/feed/svg":          {MaxAge: 1800, SMaxAge: 1800, StaleWhileRevalidate: 86400},
+	"/api/packages/svg":      {MaxAge: 3600, SMaxAge: 3600, StaleWhileRevalidate: 86400},
+	"/api/trend/svg":         {MaxAge: 3600, SMaxAge: 3600, StaleWhileRevalidate: 86400},
+}
+
+// defaultCardParams holds the value every card uses for a query parameter
+// that is left out, and cardParams the ones particular to an endpoint.
+var (
+	defaultCardParams = map[string]string{
+		"color":        "red",
+		"background":   "black",
+		"last_updated": "false",
+	}
+	cardParams = map[string]map[string]string{
+		"/api/stackexchange/svg": {"site": "stackoverflow"},
+		"/api/wakatime/svg":      {"range": "last_7_days"},
+		"/api/feed/svg":          {"limit": "5"},
+		"/api/packages/svg":      {"style": "card", "metric": "version"},
+		"/api/trend/svg":         {"days": "30"},
+	}
+)
+
+// CardCache serves a rendered SVG card again for requests with the same path
+// and query parameters, and caches the cards the next handler renders. Cards
+// are sent with Cache-Control, ETag and Last-Modified headers, and a request
+// whose If-None-Match matches the card gets a 304.
+func CardCache(c *fiber.Ctx) error {
+	endpoint := c.Path()
+	key := endpoint + "?" + normalizedQuery(c, endpoint)
+
+	card, ok := utils.LoadCachedCard(endpoint, key)
+	if ok {
+		// The card's data is still in demand, so keep it warm.
+		utils.TrackRequests(card.Keys...)
+		c.Set("X-Cache", "HIT")
+		c.Set("Content-Type", "image/svg+xml")
+		c.Response().SetBodyString(card.SVG)
+		if !card.LastModified.IsZero() {
+			c.Set("Last-Modified", card.LastModified.UTC().Format(http.TimeFormat))
+		}
+	} else {
+		c.Set("X-Cache", "MISS")
+		ctx, fetchedKeys := utils.WithFetchedKeys(c.UserContext())
+		c.SetUserContext(ctx)
+		if err := c.Next(); err != nil {
+			return err
+		}
+		// Error cards set their own headers and are never kept.
+		if c.GetRespHeader("X-Error-Status") != "" {
+			return nil
+		}
+		if c.Response().StatusCode() != fiber.StatusOK ||
+			!strings.HasPrefix(string(c.Response().Header.ContentType()), "image/svg+xml") {
+			return nil
+		}
+
+		card = models.RenderedCard{SVG: string(c.Response().Body()), Keys: fetchedKeys()}
+		card.LastModified, _ = http.ParseTime(c.GetRespHeader("Last-Modified"))
+
+		// Cards rendered from stale data are not kept, so the next request
+		// picks up the refreshed data as soon as it arrives.
+		if c.GetRespHeader("X-Cache") != "STALE" {
+			utils.StoreCachedCard(key, card)
+		}
+	}
+
+	c.Set("Cache-Control", cacheControl(c, endpoint))
+
+	etag := cardETag(card.SVG)
+	c.Set("ETag", etag)
+	if etagMatches(c.Get("If-None-Match"), etag) {
+		c.Status(fiber.StatusNotModified)
+		c.Response().ResetBody()
+	}
+	return nil
+}
+
+// cacheControl builds the Cache-Control header for endpoint. The
+// cache_seconds query parameter overrides max-age and s-maxage within the
+// bounds the server allows, and stale cards are never cached downstream.
+func cacheControl(c *fiber.Ctx, endpoint string) string {
+	if c.GetRespHeader("X-Cache") == "STALE" {
+		return "no-cache"
+	}
+
+	env := config.Current()
+
+	defaults, exists := cardCachePolicies[endpoint]
+	if !exists {
+		defaults = defaultCardCachePolicy
+	}
+	policy := env.CardCachePolicy(cardName(endpoint), defaults)
+
+	if seconds := c.QueryInt("cache_seconds", -1); seconds >= 0 {
+		minSeconds, maxSeconds := env.CardCacheBounds()
+		seconds = max(minSeconds, min(seconds, maxSeconds))
+		policy.MaxAge = seconds
+		policy.SMaxAge = seconds
+	}
+
+	return fmt.Sprintf("public, max-age=%d, s-maxage=%d, stale-while-revalidate=%d",
+		policy.MaxAge, policy.SMaxAge, policy.StaleWhileRevalidate)
+}
+
+// cardName is the name an endpoint's settings use, e.g. "stats" for
+// /api/stats/svg.
+func cardName(endpoint string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(endpoint, "/api/"), "/svg")
+}
+
+func cardETag(svg string) string {
+	sum := sha256.Sum256([]byte(svg))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizedQuery encodes the query parameters of a card from endpoint
+// sorted by name, so the same card requested with its parameters in another
+// order or its defaults spelled out shares an entry. The username keeps its
+// case, since cards show it as requested. Empty parameters are left out, and
+// so is cache_seconds, which only affects headers.
+func normalizedQuery(c *fiber.Ctx, endpoint string) string {
+	params := url.Values{}
+	c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		name, v := string(key), strings.TrimSpace(string(value))
+		defaultValue, exists := cardParams[endpoint][name]
+		if !exists {
+			defaultValue, exists = defaultCardParams[name]
+		}
+		if name == "cache_seconds" || v == "" || (exists && v == defaultValue) {
+			return
+		}
+		params.Add(name, v)
+	})
+	return params.Encode()
+}
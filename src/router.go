@@ -2,16 +2,47 @@ package src
 
 import (
 	"my-realm/src/controllers"
+	"my-realm/src/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupRoutes(app *fiber.App) {
 	app.Get("/api/languages", controllers.GetMostUsedLanguages)
-	app.Get("/api/languages/svg", controllers.GetLanguagesAsSVG)
+	app.Get("/api/languages/svg", middleware.CardCache, controllers.GetLanguagesAsSVG)
 	app.Get("/api/stats", controllers.GetProfileStats)
-	app.Get("/api/stats/svg", controllers.GetStatsAsSVG)
+	app.Get("/api/stats/svg", middleware.CardCache, controllers.GetStatsAsSVG)
 
 	app.Get("/api/leetcode", controllers.GetLeetCodeStats)
-	app.Get("/api/leetcode/svg", controllers.GetLeetCodeStatsAsSVG)
+	app.Get("/api/leetcode/svg", middleware.CardCache, controllers.GetLeetCodeStatsAsSVG)
+
+	app.Get("/api/atcoder", controllers.GetAtCoderStats)
+	app.Get("/api/atcoder/svg", middleware.CardCache, controllers.GetAtCoderStatsAsSVG)
+
+	app.Get("/api/codechef", controllers.GetCodeChefStats)
+	app.Get("/api/codechef/svg", middleware.CardCache, controllers.GetCodeChefStatsAsSVG)
+
+	app.Get("/api/stackexchange", controllers.GetStackExchangeStats)
+	app.Get("/api/stackexchange/svg", middleware.CardCache, controllers.GetStackExchangeStatsAsSVG)
+
+	app.Get("/api/wakatime", controllers.GetWakaTimeStats)
+	app.Get("/api/wakatime/svg", middleware.CardCache, controllers.GetWakaTimeStatsAsSVG)
+
+	app.Get("/api/exercism", controllers.GetExercismStats)
+	app.Get("/api/exercism/svg", middleware.CardCache, controllers.GetExercismStatsAsSVG)
+	app.Get("/api/codewars", controllers.GetCodewarsStats)
+	app.Get("/api/codewars/svg", middleware.CardCache, controllers.GetCodewarsStatsAsSVG)
+	app.Get("/api/practice", controllers.GetCombinedPracticeStats)
+	app.Get("/api/practice/svg", middleware.CardCache, controllers.GetCombinedPracticeStatsAsSVG)
+
+	app.Get("/api/feed", controllers.GetFeed)
+	app.Get("/api/feed/svg", middleware.CardCache, controllers.GetFeedAsSVG)
+
+	app.Get("/api/packages", controllers.GetPackageStats)
+	app.Get("/api/packages/svg", middleware.CardCache, controllers.GetPackageStatsAsSVG)
+
+	app.Get("/api/history", controllers.GetHistory)
+	app.Get("/api/trend/svg", middleware.CardCache, controllers.GetTrendAsSVG)
+
+	app.Get("/api/status/ratelimit", controllers.GetRateLimitStatus)
 }
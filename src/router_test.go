@@ -0,0 +1,682 @@
+package src_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"my-realm/internal/config"
+	"my-realm/internal/history"
+	"my-realm/internal/models"
+	"my-realm/internal/utils"
+	"my-realm/src"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type routeCase struct {
+	name        string
+	path        string
+	status      int
+	contentType string
+	contains    string
+}
+
+const (
+	jsonType = "application/json"
+	svgType  = "image/svg+xml"
+)
+
+func newTestApp(t *testing.T) (*fiber.App, *fakeUpstream) {
+	t.Helper()
+
+	fake := newFakeUpstream(t)
+	fake.install(t)
+
+	setEnv(t, map[string]string{
+		"GITHUB_TOKEN":       "test-token",
+		"WAKATIME_API_KEY":   "waka_test",
+		"FEED_ALLOWED_HOSTS": "127.0.0.1",
+	})
+
+	app := fiber.New()
+	src.SetupRoutes(app)
+	return app, fake
+}
+
+// setEnv sets environment variables for the rest of the test and reloads
+// the configuration, which the server otherwise reads once at startup.
+func setEnv(t *testing.T, vars map[string]string) {
+	t.Helper()
+
+	for key, value := range vars {
+		t.Setenv(key, value)
+	}
+	env, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading configuration: %v", err)
+	}
+	previous := config.Current()
+	config.Set(env)
+	t.Cleanup(func() { config.Set(previous) })
+}
+
+func runRouteCases(t *testing.T, app *fiber.App, cases []routeCase) {
+	t.Helper()
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tc.path, http.NoBody), -1)
+			if err != nil {
+				t.Fatalf("GET %s: %v", tc.path, err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("reading body: %v", err)
+			}
+
+			if resp.StatusCode != tc.status {
+				t.Fatalf("GET %s: status = %d, want %d; body: %s", tc.path, resp.StatusCode, tc.status, body)
+			}
+			if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, tc.contentType) {
+				t.Errorf("GET %s: Content-Type = %q, want %q", tc.path, got, tc.contentType)
+			}
+			if !strings.Contains(string(body), tc.contains) {
+				t.Errorf("GET %s: body does not contain %q:\n%s", tc.path, tc.contains, body)
+			}
+		})
+	}
+}
+
+func TestGitHubRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"languages", "/api/languages?username=alice", 200, jsonType, `"Go":66.66`},
+		{"languages not found", "/api/languages?username=ghost", 404, jsonType, "Not Found"},
+		{"languages upstream down", "/api/languages?username=down", 503, jsonType, "Service Unavailable"},
+		{"languages malformed", "/api/languages?username=broken", 500, jsonType, "Internal Server Error"},
+		{"languages svg", "/api/languages/svg?username=alice", 200, svgType, "TypeScript"},
+		{"languages svg malformed", "/api/languages/svg?username=broken", 200, svgType, "Internal Server Error"},
+
+		{"stats", "/api/stats?username=alice", 200, jsonType, `"total_contributions":137`},
+		{"stats stars", "/api/stats?username=alice", 200, jsonType, `"followers":42,"stars":321`},
+		{"stats not found", "/api/stats?username=ghost", 404, jsonType, "Not Found"},
+		{"stats malformed", "/api/stats?username=broken", 500, jsonType, "Internal Server Error"},
+		{"stats svg", "/api/stats/svg?username=alice", 200, svgType, "137"},
+		{"stats svg upstream down", "/api/stats/svg?username=down", 200, svgType, "Service Unavailable"},
+		{"stats svg malformed", "/api/stats/svg?username=broken", 200, svgType, "Internal Server Error"},
+
+		{"invalid login", "/api/stats?username=" + url.QueryEscape(`alice") { id }`), 400, jsonType, "Bad Request"},
+		{"login too long", "/api/languages?username=" + strings.Repeat("a", 40), 400, jsonType, "Bad Request"},
+		{"legacy login with double hyphen", "/api/stats/svg?username=al--ice", 200, svgType, "137"},
+		{"legacy login with trailing hyphen", "/api/languages?username=alice-", 200, jsonType, `"Go":66.66`},
+		{"login with leading hyphen", "/api/stats/svg?username=-alice", 200, svgType, "Bad Request"},
+		{"svg invalid login", "/api/languages/svg?username=" + url.QueryEscape("<script>"), 200, svgType, "Bad Request"},
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		setEnv(t, map[string]string{"GITHUB_TOKEN": ""})
+		runRouteCases(t, app, []routeCase{
+			{"stats", "/api/stats?username=nadia", 503, jsonType, "GitHub stats need GITHUB_TOKEN"},
+			{"stats svg", "/api/stats/svg?username=nadia", 200, svgType, "GITHUB_TOKEN"},
+		})
+	})
+}
+
+func TestCardCache(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	get := func(path string) (body, xCache string) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, http.NoBody), -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("reading body: %v", err)
+		}
+		return string(data), resp.Header.Get("X-Cache")
+	}
+
+	first, xCache := get("/api/stats/svg?username=carol&color=blue")
+	if xCache != "MISS" || !strings.Contains(first, "@carol") {
+		t.Fatalf("first card: X-Cache = %q, want MISS for @carol", xCache)
+	}
+
+	if card, _ := utils.LoadCachedCard("/api/stats/svg", "/api/stats/svg?color=blue&username=carol"); !slices.Contains(card.Keys, "github::carol") {
+		t.Errorf("cached card keys = %v, want the GitHub stats entry it was rendered from", card.Keys)
+	}
+
+	// Another user right after must not receive carol's card.
+	if other, _ := get("/api/stats/svg?username=dave&color=blue"); !strings.Contains(other, "@dave") {
+		t.Errorf("card for dave does not mention @dave:\n%s", other)
+	}
+	if green, _ := get("/api/stats/svg?username=carol&color=green"); green == first {
+		t.Error("card with another color was served from the cache")
+	}
+
+	again, xCache := get("/api/stats/svg?color=blue&username=carol&background=")
+	if xCache != "HIT" || again != first {
+		t.Errorf("reordered parameters: X-Cache = %q, want HIT with the same card", xCache)
+	}
+
+	normalized, xCache := get("/api/stats/svg?username=carol&color=blue&background=black&last_updated=false")
+	if xCache != "HIT" || normalized != first {
+		t.Errorf("defaults spelled out: X-Cache = %q, want HIT with the same card", xCache)
+	}
+
+	if recased, xCache := get("/api/stats/svg?username=Carol&color=blue"); xCache != "MISS" || !strings.Contains(recased, "@Carol") {
+		t.Errorf("username in another case: X-Cache = %q, want MISS with a card showing it as requested", xCache)
+	}
+
+	footnoted, _ := get("/api/stats/svg?username=carol&color=blue&last_updated=true")
+	if !strings.Contains(footnoted, ">Updated ") {
+		t.Errorf("card with last_updated has no footnote:\n%s", footnoted)
+	}
+
+	stats := utils.CardCacheStats()["/api/stats/svg"]
+	if stats.Hits < 1 || stats.Misses < 3 {
+		t.Errorf("card cache stats = %+v, want at least 1 hit and 3 misses", stats)
+	}
+}
+
+func TestGitHubInstances(t *testing.T) {
+	app, fake := newTestApp(t)
+
+	setEnv(t, map[string]string{
+		"GITHUB_INSTANCES":       "work,stale,rotated",
+		"GITHUB_WORK_API_URL":    fake.url("/ghe/api/v3"),
+		"GITHUB_WORK_TOKEN":      "ghe-token",
+		"GITHUB_STALE_API_URL":   fake.url("/ghe/api/v3"),
+		"GITHUB_STALE_TOKEN":     "revoked",
+		"GITHUB_ROTATED_API_URL": fake.url("/ghe/api/v3"),
+		"GITHUB_ROTATED_TOKEN":   "revoked, ghe-token",
+	})
+
+	runRouteCases(t, app, []routeCase{
+		{"languages", "/api/languages?username=alice&instance=work", 200, jsonType, `"Java":33.33`},
+		{"languages svg", "/api/languages/svg?username=alice&instance=WORK", 200, svgType, "Java"},
+		{"stats", "/api/stats?username=alice&instance=work", 200, jsonType, `"total_contributions":137`},
+		{"bad token", "/api/stats?username=alice&instance=stale", 500, jsonType, "Internal Server Error"},
+		{"rotated token", "/api/stats?username=alice&instance=rotated", 200, jsonType, `"total_contributions":137`},
+		{"rotated token repos", "/api/languages?username=alice&instance=rotated", 200, jsonType, `"Java":33.33`},
+		{"enterprise login", "/api/stats?username=alice_acme&instance=work", 200, jsonType, `"total_contributions":137`},
+		{"enterprise login on github.com", "/api/stats?username=alice_acme", 400, jsonType, "Bad Request"},
+		{"unknown instance", "/api/languages?username=alice&instance=nope", 400, jsonType, "Bad Request"},
+		{"svg unknown instance", "/api/stats/svg?username=alice&instance=nope", 200, svgType, "Bad Request"},
+	})
+}
+
+func TestCardHTTPCaching(t *testing.T) {
+	app, _ := newTestApp(t)
+	setEnv(t, map[string]string{"CARD_CACHE_LEETCODE": "max-age=120, stale-while-revalidate=600"})
+
+	get := func(path string, header http.Header) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	for _, tc := range []struct {
+		path         string
+		cacheControl string
+	}{
+		{"/api/stats/svg?username=erin", "public, max-age=600, s-maxage=600, stale-while-revalidate=86400"},
+		{"/api/packages/svg?name=left-pad", "public, max-age=3600, s-maxage=3600, stale-while-revalidate=86400"},
+		{"/api/leetcode/svg?username=erin", "public, max-age=120, s-maxage=600, stale-while-revalidate=600"},
+		{"/api/stats/svg?username=erin&cache_seconds=1800", "public, max-age=1800, s-maxage=1800, stale-while-revalidate=86400"},
+		{"/api/stats/svg?username=erin&cache_seconds=5", "public, max-age=60, s-maxage=60, stale-while-revalidate=86400"},
+		{"/api/stats/svg?username=erin&cache_seconds=9999999", "public, max-age=86400, s-maxage=86400, stale-while-revalidate=86400"},
+	} {
+		resp := get(tc.path, nil)
+		if got := resp.Header.Get("Cache-Control"); got != tc.cacheControl {
+			t.Errorf("GET %s: Cache-Control = %q, want %q", tc.path, got, tc.cacheControl)
+		}
+	}
+
+	first := get("/api/stats/svg?username=erin", nil)
+	etag := first.Header.Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || len(etag) < 10 {
+		t.Fatalf("ETag = %q, want a strong entity tag", etag)
+	}
+	if _, err := http.ParseTime(first.Header.Get("Last-Modified")); err != nil {
+		t.Errorf("Last-Modified = %q: %v", first.Header.Get("Last-Modified"), err)
+	}
+
+	notModified := get("/api/stats/svg?username=erin", http.Header{"If-None-Match": {`"other", ` + etag}})
+	body, _ := io.ReadAll(notModified.Body)
+	if notModified.StatusCode != http.StatusNotModified || len(body) != 0 {
+		t.Errorf("If-None-Match with the current ETag: status = %d, body = %q; want an empty 304", notModified.StatusCode, body)
+	}
+	if got := notModified.Header.Get("ETag"); got != etag {
+		t.Errorf("304 ETag = %q, want %q", got, etag)
+	}
+
+	changed := get("/api/stats/svg?username=erin&color=green", http.Header{"If-None-Match": {etag}})
+	if changed.StatusCode != http.StatusOK {
+		t.Errorf("If-None-Match for a different card: status = %d, want 200", changed.StatusCode)
+	}
+
+	failed := get("/api/stats/svg?username=down", nil)
+	if got := failed.Header.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("failed card: Cache-Control = %q, want no-store", got)
+	}
+	if got := failed.Header.Get("ETag"); got != "" {
+		t.Errorf("failed card: ETag = %q, want none", got)
+	}
+}
+
+func TestErrorCards(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, http.NoBody), -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("reading body: %v", err)
+		}
+		return resp, string(body)
+	}
+
+	for _, tc := range []struct {
+		path, errorStatus, message string
+	}{
+		{"/api/stats/svg?username=ghost", "404", "Not found on GitHub"},
+		{"/api/codewars/svg?username=down", "503", "Codewars is unavailable right now"},
+		{"/api/atcoder/svg", "400", "missing required fields"},
+	} {
+		resp, body := get(tc.path)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Error-Status") != tc.errorStatus {
+			t.Errorf("GET %s: status = %d, X-Error-Status = %q; want 200 with %s",
+				tc.path, resp.StatusCode, resp.Header.Get("X-Error-Status"), tc.errorStatus)
+		}
+		if !strings.Contains(body, tc.message) {
+			t.Errorf("GET %s: card does not say %q:\n%s", tc.path, tc.message, body)
+		}
+	}
+
+	resp, body := get("/api/leetcode/svg?username=limited&color=blue&background=white")
+	if got := resp.Header.Get("Retry-After"); got != "120" {
+		t.Errorf("rate limited card: Retry-After = %q, want 120", got)
+	}
+	if !strings.Contains(body, "try again in 2 minutes") || !strings.Contains(body, utils.ColorSchemes["blue"]) {
+		t.Errorf("rate limited card is not a blue card asking to wait:\n%s", body)
+	}
+
+	if again, _ := get("/api/leetcode/svg?username=limited&color=blue&background=white"); again.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("error card was cached: X-Cache = %q, want MISS", again.Header.Get("X-Cache"))
+	}
+
+	resp, body = get("/api/leetcode?username=limited")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "120" {
+		t.Errorf("rate limited JSON: status = %d, Retry-After = %q; want 429 with 120", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if !strings.Contains(body, "LeetCode is rate limiting requests") {
+		t.Errorf("rate limited JSON does not name LeetCode: %s", body)
+	}
+}
+
+func TestUpstreamTimeouts(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	previous := utils.DefaultUpstreamTimeout
+	utils.DefaultUpstreamTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { utils.DefaultUpstreamTimeout = previous })
+
+	runRouteCases(t, app, []routeCase{
+		{"json", "/api/leetcode?username=slow", 504, jsonType, "LeetCode took too long to respond"},
+		{"svg", "/api/stats/svg?username=slow", 200, svgType, "GitHub took too long to respond"},
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	paths := []string{"/api/leetcode?username=rita"}
+	for range 5 {
+		paths = append(paths, "/api/leetcode?username=down")
+	}
+	for _, path := range paths {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, http.NoBody), -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/leetcode?username=alice", http.NoBody), -1)
+	if err != nil {
+		t.Fatalf("GET /api/leetcode: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("uncached user while the circuit is open = %d with Retry-After %q, want 503 with a Retry-After",
+			resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	runRouteCases(t, app, []routeCase{
+		{"cached", "/api/leetcode?username=rita", 200, jsonType, "totalSolved"},
+		{"card", "/api/leetcode/svg?username=alice", 200, svgType, "LeetCode is unavailable right now"},
+		{"other upstream", "/api/stats?username=alice", 200, jsonType, "total_contributions"},
+		{"status", "/api/status/ratelimit", 200, jsonType, `"circuit":"open"`},
+	})
+}
+
+func TestConcurrentFetchesAreCoalesced(t *testing.T) {
+	app, fake := newTestApp(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/stats?username=slowpoke", http.NoBody), -1)
+			if err != nil {
+				t.Errorf("GET /api/stats: %v", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("GET /api/stats: status = %d, want 200", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := fake.githubGraphQLCalls.Load(); calls != 1 {
+		t.Errorf("GitHub GraphQL was called %d times, want 1", calls)
+	}
+}
+
+func TestLeetCodeRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"stats", "/api/leetcode?username=alice", 200, jsonType, `"totalSolved":160`},
+		{"contest rating", "/api/leetcode?username=alice", 200, jsonType, `"contestRating":1843.6`},
+		{"missing username", "/api/leetcode", 400, jsonType, "Username is required"},
+		{"invalid username", "/api/leetcode?username=" + url.QueryEscape("alice bob"), 400, jsonType, "Bad Request"},
+		{"not found", "/api/leetcode?username=ghost", 404, jsonType, "Not Found"},
+		{"malformed", "/api/leetcode?username=broken", 500, jsonType, "Internal Server Error"},
+		{"svg", "/api/leetcode/svg?username=alice", 200, svgType, "160"},
+		{"svg upstream down", "/api/leetcode/svg?username=down", 200, svgType, "Service Unavailable"},
+	})
+}
+
+func TestAtCoderRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"stats", "/api/atcoder?username=alice", 200, jsonType, `"rating":1250`},
+		{"missing username", "/api/atcoder", 400, jsonType, "Missing"},
+		{"invalid username", "/api/atcoder?username=al", 400, jsonType, "Bad Request"},
+		{"not found", "/api/atcoder?username=ghost", 404, jsonType, "Not Found"},
+		{"malformed", "/api/atcoder?username=broken", 500, jsonType, "Internal Server Error"},
+		{"svg", "/api/atcoder/svg?username=alice", 200, svgType, "1250"},
+		{"svg upstream down", "/api/atcoder/svg?username=down", 200, svgType, "Service Unavailable"},
+	})
+}
+
+func TestCodeChefRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"stats", "/api/codechef?username=alice", 200, jsonType, `"rating":1834`},
+		{"missing username", "/api/codechef", 400, jsonType, "Missing"},
+		{"not found", "/api/codechef?username=ghost", 404, jsonType, "Not Found"},
+		{"malformed", "/api/codechef?username=broken", 500, jsonType, "Internal Server Error"},
+		{"svg", "/api/codechef/svg?username=alice", 200, svgType, "1834"},
+		{"svg upstream down", "/api/codechef/svg?username=down", 200, svgType, "Service Unavailable"},
+	})
+}
+
+func TestStackExchangeRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"stats", "/api/stackexchange?id=22656", 200, jsonType, `"reputation":1500000`},
+		{"missing id", "/api/stackexchange", 400, jsonType, "Missing"},
+		{"non-numeric id", "/api/stackexchange?id=alice", 400, jsonType, "Bad Request"},
+		{"not found", "/api/stackexchange?id=404", 404, jsonType, "Not Found"},
+		{"malformed", "/api/stackexchange?id=500", 500, jsonType, "Internal Server Error"},
+		{"svg", "/api/stackexchange/svg?id=22656", 200, svgType, "1500000"},
+		{"svg upstream down", "/api/stackexchange/svg?id=502", 200, svgType, "Service Unavailable"},
+	})
+}
+
+func TestWakaTimeRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"stats", "/api/wakatime", 200, jsonType, `"totalSeconds":36000`},
+		{"public user", "/api/wakatime?username=alice&range=last_30_days", 200, jsonType, `"range":"last_30_days"`},
+		{"unknown range", "/api/wakatime?range=forever", 400, jsonType, "Bad Request"},
+		{"not found", "/api/wakatime?username=ghost", 404, jsonType, "Not Found"},
+		{"malformed", "/api/wakatime?username=broken", 500, jsonType, "Internal Server Error"},
+		{"svg", "/api/wakatime/svg", 200, svgType, "10.0 hrs"},
+		{"svg upstream down", "/api/wakatime/svg?username=down", 200, svgType, "Service Unavailable"},
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		setEnv(t, map[string]string{"WAKATIME_API_KEY": ""})
+		runRouteCases(t, app, []routeCase{
+			{"current user", "/api/wakatime?range=last_year", 503, jsonType, "Service Unavailable"},
+		})
+	})
+}
+
+func TestPracticeRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"exercism", "/api/exercism?username=alice", 200, jsonType, `"score":1234`},
+		{"exercism missing username", "/api/exercism", 400, jsonType, "Missing"},
+		{"exercism invalid username", "/api/exercism?username=" + url.QueryEscape("../admin"), 400, jsonType, "Bad Request"},
+		{"exercism not found", "/api/exercism?username=ghost", 404, jsonType, "Not Found"},
+		{"exercism malformed", "/api/exercism?username=broken", 500, jsonType, "Internal Server Error"},
+		{"exercism svg", "/api/exercism/svg?username=alice", 200, svgType, "Rust"},
+		{"exercism svg upstream down", "/api/exercism/svg?username=down", 200, svgType, "Service Unavailable"},
+
+		{"codewars", "/api/codewars?username=alice", 200, jsonType, `"rank":"4 kyu"`},
+		{"codewars not found", "/api/codewars?username=ghost", 404, jsonType, "Not Found"},
+		{"codewars malformed", "/api/codewars?username=broken", 500, jsonType, "Internal Server Error"},
+		{"codewars svg", "/api/codewars/svg?username=alice", 200, svgType, "Python"},
+
+		{"combined", "/api/practice?exercism=alice&codewars=alice", 200, jsonType, `"platform":"Codewars"`},
+		{"combined missing", "/api/practice", 400, jsonType, "Missing"},
+		{"combined partial failure", "/api/practice?exercism=alice&codewars=down", 503, jsonType, "Service Unavailable"},
+		{"combined svg", "/api/practice/svg?exercism=alice&codewars=alice", 200, svgType, "Codewars"},
+		{"combined svg malformed", "/api/practice/svg?codewars=broken", 200, svgType, "Internal Server Error"},
+	})
+}
+
+func TestFeedRoutes(t *testing.T) {
+	app, fake := newTestApp(t)
+
+	feed := func(name string) string {
+		return url.QueryEscape(fake.feedURL(name))
+	}
+
+	runRouteCases(t, app, []routeCase{
+		{"rss", "/api/feed?url=" + feed("alice"), 200, jsonType, `"title":"Hello \u0026 welcome"`},
+		{"missing source", "/api/feed", 400, jsonType, "Missing"},
+		{"host not allowed", "/api/feed?url=" + url.QueryEscape("https://example.com/feed.xml"), 403, jsonType, "Forbidden"},
+		{"default host replaced", "/api/feed?url=" + url.QueryEscape("https://dev.to/feed/alice"), 403, jsonType, "Forbidden"},
+		{"rss not found", "/api/feed?url=" + feed("ghost"), 404, jsonType, "Not Found"},
+		{"rss malformed", "/api/feed?url=" + feed("broken"), 500, jsonType, "Internal Server Error"},
+		{"rss svg", "/api/feed/svg?url=" + feed("alice"), 200, svgType, "Hello &amp; welcome"},
+
+		{"devto", "/api/feed?devto=alice", 200, jsonType, `"title":"Writing Go"`},
+		{"devto not found", "/api/feed?devto=ghost", 404, jsonType, "Not Found"},
+		{"devto malformed", "/api/feed?devto=broken", 500, jsonType, "Internal Server Error"},
+		{"devto svg", "/api/feed/svg?devto=alice&limit=1", 200, svgType, "Writing Go"},
+		{"svg zero limit", "/api/feed/svg?devto=alice&limit=0", 200, svgType, "Bad Request"},
+		{"svg non-numeric limit", "/api/feed/svg?devto=alice&limit=all", 200, svgType, "Bad Request"},
+
+		{"hashnode", "/api/feed?hashnode=alice", 200, jsonType, `"title":"On caching"`},
+		{"hashnode upstream down", "/api/feed?hashnode=down", 503, jsonType, "Service Unavailable"},
+		{"hashnode malformed", "/api/feed/svg?hashnode=broken", 200, svgType, "Internal Server Error"},
+	})
+}
+
+func TestPackageRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"npm", "/api/packages?registry=npm&name=left-pad", 200, jsonType, `"latestVersion":"1.2.3"`},
+		{"npm scoped", "/api/packages?registry=npm&name=@scope/pkg", 200, jsonType, `"weeklyDownloads":12345`},
+		{"pypi", "/api/packages?registry=pypi&name=requests", 200, jsonType, `"latestVersion":"2.0.0"`},
+		{"crates", "/api/packages?registry=crates&name=serde", 200, jsonType, `"totalDownloads":2500000`},
+		{"go", "/api/packages?registry=go&name=github.com/alice/Realm", 200, jsonType, `"latestVersion":"v1.4.0"`},
+		{"missing name", "/api/packages", 400, jsonType, "Missing"},
+		{"unknown registry", "/api/packages?registry=maven&name=junit", 400, jsonType, "Bad Request"},
+		{"invalid name", "/api/packages?name=../etc", 400, jsonType, "Bad Request"},
+		{"not found", "/api/packages?registry=npm&name=ghost", 404, jsonType, "Not Found"},
+		{"upstream down", "/api/packages?registry=crates&name=down", 503, jsonType, "Service Unavailable"},
+		{"malformed", "/api/packages?registry=pypi&name=broken", 500, jsonType, "Internal Server Error"},
+		{"card", "/api/packages/svg?registry=crates&name=serde", 200, svgType, "2.5M"},
+		{"badge", "/api/packages/svg?registry=npm&name=left-pad&style=badge&metric=weekly", 200, svgType, "12.3k/week"},
+		{"svg malformed", "/api/packages/svg?registry=go&name=example.com/broken", 200, svgType, "Internal Server Error"},
+	})
+}
+
+func TestHistoryRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	runRouteCases(t, app, []routeCase{
+		{"history disabled", "/api/history?provider=leetcode&username=alice&metric=total_solved", 503, jsonType, "Service Unavailable"},
+	})
+
+	store, err := history.NewSQLite(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("opening history database: %v", err)
+	}
+	utils.SetHistory(store)
+	t.Cleanup(func() { utils.SetHistory(nil) })
+
+	// An older snapshot, one past retention, and today's taken twice.
+	for _, snapshot := range []models.Snapshot{
+		{Provider: "leetcode", Username: "alice", TakenAt: time.Now().AddDate(0, 0, -3), Metrics: map[string]float64{"total_solved": 90}},
+		{Provider: "leetcode", Username: "alice", TakenAt: time.Now().AddDate(-2, 0, 0), Metrics: map[string]float64{"total_solved": 10}},
+	} {
+		if err := store.Record(snapshot); err != nil {
+			t.Fatalf("recording snapshot: %v", err)
+		}
+	}
+	tracked := []models.TrackedUser{{Provider: "leetcode", Username: "alice"}, {Provider: "github", Username: "Alice"}}
+	for i := 0; i < 2; i++ {
+		if err := utils.RecordSnapshots(context.Background(), tracked, 365*24*time.Hour); err != nil {
+			t.Fatalf("recording snapshots: %v", err)
+		}
+	}
+
+	today := time.Now().UTC().Format(history.DateFormat)
+	threeDaysAgo := time.Now().AddDate(0, 0, -3).UTC().Format(history.DateFormat)
+	runRouteCases(t, app, []routeCase{
+		{"leetcode series", "/api/history?provider=leetcode&username=alice&metric=total_solved",
+			200, jsonType, `"points":[{"date":"` + threeDaysAgo + `","value":90},{"date":"` + today + `","value":`},
+		{"github series", "/api/history?provider=github&username=alice&metric=contributions",
+			200, jsonType, `"points":[{"date":"` + today + `","value":137}]`},
+		{"username case", "/api/history?provider=github&username=ALICE&metric=contributions",
+			200, jsonType, `"points":[{"date":"` + today + `","value":137}]`},
+		{"untracked user", "/api/history?provider=github&username=bob&metric=contributions", 200, jsonType, `"points":[]`},
+		{"unknown metric", "/api/history?provider=leetcode&username=alice&metric=stars", 400, jsonType, "Bad Request"},
+		{"missing metric", "/api/history?provider=leetcode&username=alice", 400, jsonType, "Missing Fields"},
+		{"invalid username", "/api/history?provider=github&username=-alice&metric=stars", 400, jsonType, "Bad Request"},
+
+		{"trend", "/api/trend/svg?provider=leetcode&username=alice&metric=total_solved", 200, svgType, "+70 this month"},
+		{"trend value", "/api/trend/svg?provider=leetcode&username=alice&metric=total_solved&days=365", 200, svgType, ">160</text>"},
+		{"trend stars", "/api/trend/svg?provider=github&username=alice&metric=stars&days=90", 200, svgType, "No change this quarter"},
+		{"trend no history", "/api/trend/svg?provider=github&username=bob&metric=followers", 200, svgType, "No history yet"},
+		{"trend bad window", "/api/trend/svg?provider=github&username=alice&metric=stars&days=7", 200, svgType, "Bad Request"},
+		{"trend unknown metric", "/api/trend/svg?provider=github&username=alice&metric=rating", 200, svgType, "Bad Request"},
+	})
+}
+
+func TestRateLimitStatus(t *testing.T) {
+	app, _ := newTestApp(t)
+	setEnv(t, map[string]string{"GITHUB_TOKEN": "test-token, ghp_unused_spare_token"})
+
+	for _, path := range []string{"/api/stats/svg?username=rita", "/api/stats/svg?username=rita", "/api/leetcode?username=rita", "/api/stackexchange?id=22656"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, http.NoBody), -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/status/ratelimit", http.NoBody), -1)
+	if err != nil {
+		t.Fatalf("GET /api/status/ratelimit: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, body: %s", resp.StatusCode, body)
+	}
+	if strings.Contains(string(body), "test-token") || strings.Contains(string(body), "ghp_unused_spare_token") {
+		t.Errorf("status leaks a token: %s", body)
+	}
+
+	var response struct {
+		Data models.RateLimitStatus `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("decoding status: %v", err)
+	}
+	status := response.Data
+
+	var graphQL *models.TokenStatus
+	for i, token := range status.GitHub {
+		if token.Instance == "" && token.Token == "**********" && token.Resource == "graphql" {
+			graphQL = &status.GitHub[i]
+		}
+	}
+	if graphQL == nil || graphQL.Remaining != 4990 || graphQL.Requests < 1 || graphQL.Reset == nil {
+		t.Errorf("graphql quota of the default token = %+v, want 4990 remaining after a request", graphQL)
+	}
+	if len(status.GitHub) != 4 {
+		t.Errorf("got %d token statuses, want core and graphql for 2 tokens", len(status.GitHub))
+	}
+
+	github := status.Upstreams["github"]
+	if github.Fetches < 1 || github.Cache.Misses < 1 {
+		t.Errorf("github upstream status = %+v, want a miss and a fetch", github)
+	}
+	if leetcode, ok := status.Upstreams["leetcode"]; !ok || leetcode.Quota != nil {
+		t.Errorf("upstreams = %v, want leetcode without a quota", status.Upstreams)
+	}
+	if quota := status.Upstreams["stackexchange"].Quota; quota == nil || quota.Remaining != 290 || quota.Max != 300 {
+		t.Errorf("stackexchange quota = %+v, want 290 of 300 left", quota)
+	}
+	if cards := status.Cards["/api/stats/svg"]; cards.Hits < 1 || cards.HitRatio <= 0 {
+		t.Errorf("stats card cache = %+v, want a hit", cards)
+	}
+}
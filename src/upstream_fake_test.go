@@ -0,0 +1,353 @@
+package src_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"my-realm/internal/models"
+	"my-realm/internal/utils"
+)
+
+// The fake upstream decides how to answer from the requested user or
+// package name, so every route can be driven into each failure mode:
+//
+//	ghost   -> 404 from the upstream
+//	down    -> 502 from the upstream
+//	limited -> 429 asking to retry in two minutes
+//	slow    -> no answer until the request is abandoned
+//	broken  -> 200 with a malformed body
+//
+// Any other name gets a well-formed response.
+const (
+	userGhost   = "ghost"
+	userDown    = "down"
+	userLimited = "limited"
+	userSlow    = "slow"
+	userBroken  = "broken"
+)
+
+type fakeUpstream struct {
+	server *httptest.Server
+
+	// githubGraphQLCalls counts the GraphQL requests that reached github.com.
+	githubGraphQLCalls atomic.Int64
+}
+
+func newFakeUpstream(t *testing.T) *fakeUpstream {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	fake := &fakeUpstream{server: httptest.NewServer(mux)}
+	t.Cleanup(fake.server.Close)
+
+	// github.com and a GitHub Enterprise Server, each accepting only its own
+	// token and answering with its own repositories.
+	for _, host := range []struct {
+		api, graphQL, token, language string
+	}{
+		{"/github", "/github/graphql", "test-token", "TypeScript"},
+		{"/ghe/api/v3", "/ghe/api/graphql", "ghe-token", "Java"},
+	} {
+		authorized := func(w http.ResponseWriter, r *http.Request) bool {
+			if r.Header.Get("Authorization") != "Bearer "+host.token {
+				http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+				return false
+			}
+			return true
+		}
+
+		mux.HandleFunc("GET "+host.api+"/users/{user}/repos", func(w http.ResponseWriter, r *http.Request) {
+			if !authorized(w, r) {
+				return
+			}
+			serve(w, r, r.PathValue("user"), "application/json", fmt.Sprintf(`[
+				{"language": "Go"}, {"language": "Go"}, {"language": %q}, {"language": null}
+			]`, host.language))
+		})
+		mux.HandleFunc("POST "+host.graphQL, func(w http.ResponseWriter, r *http.Request) {
+			if !authorized(w, r) {
+				return
+			}
+			if host.api == "/github" {
+				fake.githubGraphQLCalls.Add(1)
+			}
+			username := graphQLUsername(r)
+			if strings.HasPrefix(username, "slow") {
+				time.Sleep(100 * time.Millisecond)
+			}
+			serve(w, r, username, "application/json", `{"data": {"user": {"contributionsCollection": {
+				"totalCommitContributions": 120,
+				"totalPullRequestContributions": 14,
+				"totalIssueContributions": 3,
+				"contributionCalendar": {"totalContributions": 137, "weeks": [{"contributionDays": [
+					{"contributionCount": 1, "date": "2024-06-02", "weekday": 0},
+					{"contributionCount": 4, "date": "2024-06-03", "weekday": 1},
+					{"contributionCount": 0, "date": "2024-06-04", "weekday": 2}
+				]}]}
+			},
+			"followers": {"totalCount": 42},
+			"repositories": {"nodes": [{"stargazerCount": 300}, {"stargazerCount": 21}]}
+			},
+			"rateLimit": {"remaining": 4990, "resetAt": "2099-01-01T00:00:00Z", "cost": 1}
+			}}`)
+		})
+	}
+	mux.HandleFunc("POST /leetcode/graphql", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, graphQLUsername(r), "application/json", `{"data": {
+			"allQuestionsCount": [
+				{"difficulty": "All", "count": 0},
+				{"difficulty": "Easy", "count": 800},
+				{"difficulty": "Medium", "count": 1600},
+				{"difficulty": "Hard", "count": 700}
+			],
+			"matchedUser": {
+				"profile": {"ranking": 12345},
+				"submitStats": {
+					"acSubmissionNum": [
+						{"difficulty": "Easy", "count": 100, "submissions": 120},
+						{"difficulty": "Medium", "count": 50, "submissions": 80},
+						{"difficulty": "Hard", "count": 10, "submissions": 30}
+					],
+					"totalSubmissionNum": [
+						{"difficulty": "Easy", "count": 110, "submissions": 200},
+						{"difficulty": "Medium", "count": 60, "submissions": 150},
+						{"difficulty": "Hard", "count": 20, "submissions": 50}
+					]
+				}
+			},
+			"userContestRanking": {"rating": 1843.6}
+		}}`)
+	})
+
+	mux.HandleFunc("GET /atcoder/users/{user}/history/json", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("user"), "application/json", `[
+			{"IsRated": true, "Place": 900, "OldRating": 0, "NewRating": 600, "Performance": 900,
+			 "ContestName": "AtCoder Beginner Contest 300", "EndTime": "2023-04-29T22:40:00+09:00"},
+			{"IsRated": true, "Place": 500, "OldRating": 600, "NewRating": 1250, "Performance": 1500,
+			 "ContestName": "AtCoder Beginner Contest 301", "EndTime": "2023-05-13T22:40:00+09:00"}
+		]`)
+	})
+	mux.HandleFunc("GET /atcoder/users/{user}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("user"), "text/html", `<table>
+			<tr><th class="no-break">Rating</th><td><span class="user-cyan">1250</span></td></tr>
+		</table>`)
+	})
+	mux.HandleFunc("GET /atcoder-problems/user/ac_rank", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.URL.Query().Get("user"), "application/json", `{"count": 321, "rank": 4567}`)
+	})
+
+	mux.HandleFunc("GET /codechef/users/{user}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("user"), "text/html", `<div class="rating-number">1834</div>
+			<small>(Highest Rating 1902)</small>
+			<ul><li><strong>5123</strong> Global Rank</li><li><strong>4321</strong> Country Rank</li></ul>
+			<h3>Total Problems Solved: 245</h3>`)
+	})
+
+	mux.HandleFunc("GET /stackexchange/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, stackExchangeUser(r.PathValue("id")), "application/json", `{"items": [{
+			"display_name": "Jon &amp; Co", "reputation": 1500000,
+			"badge_counts": {"gold": 900, "silver": 9000, "bronze": 9500}
+		}], "has_more": false, "quota_remaining": 290, "quota_max": 300}`)
+	})
+	mux.HandleFunc("GET /stackexchange/users/{id}/answers", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("filter") == "total" {
+			serve(w, r, stackExchangeUser(r.PathValue("id")), "application/json", `{"total": 4}`)
+			return
+		}
+		serve(w, r, stackExchangeUser(r.PathValue("id")), "application/json", `{"items": [
+			{"is_accepted": true}, {"is_accepted": false}, {"is_accepted": true}, {"is_accepted": true}
+		], "has_more": false}`)
+	})
+	mux.HandleFunc("GET /stackexchange/users/{id}/questions", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, stackExchangeUser(r.PathValue("id")), "application/json", `{"total": 2}`)
+	})
+	mux.HandleFunc("GET /stackexchange/users/{id}/top-tags", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, stackExchangeUser(r.PathValue("id")), "application/json", `{"items": [
+			{"tag_name": "c#", "answer_count": 3, "answer_score": 42, "question_count": 1}
+		], "has_more": false}`)
+	})
+
+	mux.HandleFunc("GET /wakatime/users/{user}/stats/{range}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("user") == "current" && r.Header.Get("Authorization") == "" {
+			http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		serve(w, r, r.PathValue("user"), "application/json", `{"data": {
+			"total_seconds": 36000,
+			"languages": [{"name": "Go", "total_seconds": 27000, "percent": 75}, {"name": "SQL", "total_seconds": 9000, "percent": 25}],
+			"editors": [{"name": "Neovim", "total_seconds": 36000, "percent": 100}],
+			"projects": [{"name": "my-realm", "total_seconds": 36000, "percent": 100}]
+		}}`)
+	})
+	mux.HandleFunc("GET /wakatime/users/{user}/all_time_since_today", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("user"), "application/json", `{"data": {"total_seconds": 3600000}}`)
+	})
+
+	mux.HandleFunc("GET /exercism/profiles/{user}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("user"), "application/json", `{"profile": {"handle": "alice", "reputation": "1,234"}}`)
+	})
+	mux.HandleFunc("GET /exercism/profiles/{user}/solutions", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("user"), "application/json", `{"results": [
+			{"track": {"slug": "go", "title": "Go"}},
+			{"track": {"slug": "go", "title": "Go"}},
+			{"track": {"slug": "rust", "title": "Rust"}}
+		], "meta": {"current_page": 1, "total_pages": 1}}`)
+	})
+	mux.HandleFunc("GET /codewars/users/{user}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("user"), "application/json", `{"username": "alice", "honor": 2048,
+			"ranks": {"overall": {"rank": -4, "name": "4 kyu"}, "languages": {"python": {"rank": -4, "name": "4 kyu"}}},
+			"codeChallenges": {"totalCompleted": 3}}`)
+	})
+	mux.HandleFunc("GET /codewars/users/{user}/code-challenges/completed", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("user"), "application/json", `{"totalPages": 1, "totalItems": 3, "data": [
+			{"completedLanguages": ["python"]}, {"completedLanguages": ["python", "javascript"]}, {"completedLanguages": ["python"]}
+		]}`)
+	})
+
+	mux.HandleFunc("GET /feeds/{name}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, strings.TrimSuffix(r.PathValue("name"), ".xml"), "application/rss+xml", `<?xml version="1.0"?>
+			<rss version="2.0"><channel><title>Alice's Blog</title><link>https://alice.example</link>
+				<item><title>Hello &amp; welcome</title><link>https://alice.example/hello</link>
+					<pubDate>Mon, 03 Jun 2024 10:00:00 +0000</pubDate><description>A short post.</description></item>
+			</channel></rss>`)
+	})
+	mux.HandleFunc("GET /devto/articles", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.URL.Query().Get("username"), "application/json", `[{"title": "Writing Go", "url": "https://dev.to/alice/go",
+			"published_at": "2024-06-01T10:00:00Z", "reading_time_minutes": 4, "public_reactions_count": 17}]`)
+	})
+	mux.HandleFunc("POST /hashnode", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, graphQLUsername(r), "application/json", `{"data": {"user": {"name": "Alice", "posts": {"nodes": [
+			{"title": "On caching", "url": "https://alice.hashnode.dev/caching", "publishedAt": "2024-05-01T10:00:00Z",
+			 "readTimeInMinutes": 6, "reactionCount": 9}
+		]}}}}`)
+	})
+
+	mux.HandleFunc("GET /npm/{name...}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("name"), "application/json", `{"dist-tags": {"latest": "1.2.3"},
+			"time": {"1.2.3": "2024-05-20T12:00:00Z"}}`)
+	})
+	mux.HandleFunc("GET /npm-downloads/downloads/point/last-week/{name...}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("name"), "application/json", `{"downloads": 12345}`)
+	})
+	mux.HandleFunc("GET /pypi/pypi/{name}/json", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("name"), "application/json", `{"info": {"version": "2.0.0"},
+			"urls": [{"upload_time_iso_8601": "2024-04-01T08:00:00Z"}]}`)
+	})
+	mux.HandleFunc("GET /pypistats/api/packages/{name}/recent", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("name"), "application/json", `{"data": {"last_week": 5000}}`)
+	})
+	mux.HandleFunc("GET /crates/api/v1/crates/{name}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("name"), "application/json", `{"crate": {"max_stable_version": "0.9.1", "downloads": 2500000},
+			"versions": [{"num": "0.9.1", "created_at": "2024-03-01T00:00:00Z"}]}`)
+	})
+	mux.HandleFunc("GET /goproxy/{module...}", func(w http.ResponseWriter, r *http.Request) {
+		module := strings.TrimSuffix(r.PathValue("module"), "/@latest")
+		serve(w, r, module[strings.LastIndex(module, "/")+1:], "application/json",
+			`{"Version": "v1.4.0", "Time": "2024-02-01T00:00:00Z"}`)
+	})
+
+	return fake
+}
+
+// install points every provider at the fake upstream for the rest of the test.
+func (f *fakeUpstream) install(t *testing.T) {
+	t.Helper()
+
+	base := f.server.URL
+	utils.SetUpstreams(models.Upstreams{
+		GitHubAPI:       base + "/github",
+		GitHubGraphQL:   base + "/github/graphql",
+		LeetCodeGraphQL: base + "/leetcode/graphql",
+		AtCoder:         base + "/atcoder",
+		AtCoderProblems: base + "/atcoder-problems",
+		CodeChef:        base + "/codechef",
+		StackExchange:   base + "/stackexchange",
+		WakaTime:        base + "/wakatime",
+		Exercism:        base + "/exercism",
+		Codewars:        base + "/codewars",
+		DevTo:           base + "/devto",
+		Hashnode:        base + "/hashnode",
+		NpmRegistry:     base + "/npm",
+		NpmDownloads:    base + "/npm-downloads",
+		PyPI:            base + "/pypi",
+		PyPIStats:       base + "/pypistats",
+		CratesIO:        base + "/crates",
+		GoProxy:         base + "/goproxy",
+	})
+	// Retries back off briefly so failure modes stay quick to test.
+	opts := utils.DefaultTransportOptions()
+	opts.BaseBackoff, opts.MaxBackoff = time.Millisecond, 5*time.Millisecond
+	utils.SetHTTPClient(utils.NewHTTPClient(opts))
+
+	t.Cleanup(func() {
+		utils.SetUpstreams(utils.DefaultUpstreams())
+		utils.SetHTTPClient(utils.NewHTTPClient(utils.DefaultTransportOptions()))
+	})
+}
+
+func (f *fakeUpstream) url(path string) string {
+	return f.server.URL + path
+}
+
+func (f *fakeUpstream) feedURL(name string) string {
+	return fmt.Sprintf("%s/feeds/%s.xml", f.server.URL, name)
+}
+
+func serve(w http.ResponseWriter, r *http.Request, name, contentType, body string) {
+	switch name {
+	case userGhost:
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	case userDown:
+		http.Error(w, "<html>502 Bad Gateway</html>", http.StatusBadGateway)
+	case userSlow:
+		<-r.Context().Done()
+	case userLimited:
+		w.Header().Set("Retry-After", "120")
+		http.Error(w, `{"message": "Too Many Requests"}`, http.StatusTooManyRequests)
+	case userBroken:
+		w.Header().Set("Content-Type", contentType)
+		_, _ = io.WriteString(w, `{"data": {"user": [`)
+	default:
+		w.Header().Set("Content-Type", contentType)
+		_, _ = io.WriteString(w, body)
+	}
+}
+
+// stackExchangeUser maps the numeric ids used in tests onto failure modes.
+func stackExchangeUser(id string) string {
+	switch id {
+	case "404":
+		return userGhost
+	case "502":
+		return userDown
+	case "500":
+		return userBroken
+	default:
+		return id
+	}
+}
+
+// graphQLUsername reads the user a GraphQL request is about from its
+// variables. Queries with the user inlined get an empty name.
+func graphQLUsername(r *http.Request) string {
+	var body struct {
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return ""
+	}
+
+	for _, key := range []string{"username", "login"} {
+		if name, ok := body.Variables[key].(string); ok {
+			return name
+		}
+	}
+	return ""
+}